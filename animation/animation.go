@@ -3,8 +3,10 @@ package animation
 import (
 	"log"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
+	"github.com/drichelson/ledicious/output"
 	"github.com/drichelson/ledicious/usb"
 	"github.com/golang/geo/s2"
 	"github.com/lucasb-eyer/go-colorful"
@@ -18,10 +20,13 @@ const (
 )
 
 var (
-	pixels   Pixels
-	renderCh = make(chan usb.RenderPackage, 1)
-	rows     = make([][]*Pixel, RowCount)
-	cols     = make([][]*Pixel, ColumnCount)
+	pixels Pixels
+	// paceValue holds the *pacer of the running animation. Start sets it while Stats may be reading it.
+	paceValue atomic.Value
+	rows      = make([][]*Pixel, RowCount)
+	cols      = make([][]*Pixel, ColumnCount)
+	// blanked is 1 while the globe is turned off.
+	blanked int32
 )
 
 type Animation interface {
@@ -46,48 +51,87 @@ type Pixel struct {
 	Lon      float64
 }
 
-func Start(control Control) {
+// Start runs the animation forever at the given frame rate, sending each frame to the fanout's outputs.
+// Each output holds at most one frame waiting to be sent, so the next frame is generated while the last
+// one is transferred; if an output falls behind its waiting frame is replaced by the newer one.
+//
+// The animation is the one picked with Select, and can be switched at any time; the switch happens between
+// frames, and the old animation keeps running until the transition to the new one is over.
+func Start(control Control, fanout *output.Fanout, fps float64) {
+	fanout.Start()
+
+	name := Current()
+	a, err := newAnimation(control, name)
+	if err != nil {
+		log.Fatalf("Error starting animation %s: %v", name, err)
+	}
+	startTime := time.Now()
+	r := &running{animation: a, start: startTime}
+	var t *transition
+	pace := newPacer(fps, startTime)
+	paceValue.Store(pace)
 
-	go func() {
-		for {
-			usbErr := usb.Initialize()
-			for usbErr != nil {
-				time.Sleep(1 * time.Second)
-				usbErr = usb.Initialize()
-			}
-			for {
-				renderErr := usb.Render(<-renderCh)
-				if renderErr != nil {
-					break
-				}
+	for {
+		pace.wait()
+		frameStart := time.Now()
+		if next, nextName, nextTransition := nextAnimation(control, name); next != nil {
+			// Switching again mid transition starts from the animation that was coming in.
+			if t != nil {
+				r = t.to
 			}
+			t = newTransition(nextTransition, r, &running{animation: next, start: frameStart}, frameStart)
+			name = nextName
 		}
-	}()
+		if t == nil {
+			r.frame(frameStart)
+		} else if t.frame(frameStart) {
+			r, t = t.to, nil
+		}
+		brightness := control.GetVar("brightness")
+		if Blanked() {
+			brightness = 0
+		}
+		renderPkg := pixels.render(brightness, control.GetVar("dither") > 0)
+		pixels.reset()
+		pace.generated(frameStart, time.Now())
+		fanout.Send(renderPkg)
+		pace.setDropped(fanout.Dropped())
+	}
+}
 
-	var a Animation
+// Blank turns the globe off, or back on. The animation keeps running in the dark, and the
+// brightness var is left alone, so it comes back as it was.
+func Blank(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&blanked, v)
+}
 
-	a = NewOpenSimplexAnimation(control)
-	//a = NewVuSimplexAnimation(control)
-	//a = NewGeoAnimation(control)
-	//a = NewGeoAnimation2(control)
-	//a = NewGeojsonAnimation(control)
-	//a = NewBrightnessTestAnimation(control)
-	//a = NewGradientTestAnimation(control)
-	startTime := time.Now()
-	checkPointTime := startTime
-	frameCount := 0
+func Blanked() bool {
+	return atomic.LoadInt32(&blanked) == 1
+}
 
-	for {
-		a.frame(time.Since(startTime), frameCount)
-		pixels.render(control.GetVar("brightness"))
-		pixels.reset()
-		frameCount++
-		if frameCount%1000 == 0 {
-			newCheckPointTime := time.Now()
-			log.Printf("Avg FPS for past 1000 frames: %v\n", 1000.0/time.Since(checkPointTime).Seconds())
-			checkPointTime = newCheckPointTime
+// Stats reports the frame rate and how many frames were late or dropped.
+func Stats() FrameStats {
+	p, ok := paceValue.Load().(*pacer)
+	if !ok {
+		// Nothing has been shown yet.
+		return newPacer(DefaultFPS, time.Now()).Stats()
+	}
+	return p.Stats()
+}
+
+// Positions returns the location of every pixel in render order. Pixels that aren't wired up are nil.
+func Positions() []*output.Position {
+	positions := make([]*output.Position, len(pixels.all))
+	for i, p := range pixels.all {
+		if !p.disabled {
+			positions[i] = &output.Position{Lat: p.Lat, Lon: p.Lon}
 		}
 	}
+	return positions
 }
 
 func (p Pixels) getRandomPixel() *Pixel {
@@ -100,12 +144,12 @@ func (p *Pixels) reset() {
 	}
 }
 
-func (p *Pixels) render(brightness float64) {
+func (p *Pixels) render(brightness float64, dither bool) usb.RenderPackage {
 	colors := make([]colorful.Color, len(pixels.all))
 	for i, p := range pixels.all {
 		colors[i] = *p.color
 	}
-	renderCh <- usb.RenderPackage{Pixels: colors, Brightness: brightness}
+	return usb.RenderPackage{Pixels: colors, Brightness: brightness, Dither: dither}
 }
 
 //
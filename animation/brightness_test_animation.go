@@ -34,6 +34,4 @@ func (a *BrightnessTestAnimation) frame(elapsed time.Duration, frameCount int) {
 		pixels.active[i].color = &c
 	}
 	//fmt.Printf("v: %v\n", v)
-
-	time.Sleep(500 * time.Millisecond)
 }
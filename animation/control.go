@@ -2,78 +2,275 @@ package animation
 
 import (
 	"encoding/json"
+	"fmt"
+	"github.com/launchdarkly/go-metrics"
 	"github.com/lucasb-eyer/go-colorful"
 	"log"
+	"regexp"
 	"strings"
-	"sync"
+	"time"
 )
 
+var (
+	colorHexPattern = regexp.MustCompile("^[0-9a-fA-F]{6}$")
+
+	varChanges   = metrics.GetOrRegisterCounter("control.var.changes", metrics.DefaultRegistry)
+	colorChanges = metrics.GetOrRegisterCounter("control.color.changes", metrics.DefaultRegistry)
+)
+
+// Control reads and writes params by name. A name is looked up in the animation's namespace first
+// and then in GlobalNamespace, so animations can't clash over names. Copies share the same Store.
 type Control struct {
-	Vars   map[string]float64
-	Colors map[string]string
-	mu     *sync.Mutex
+	store *Store
+	// namespace is the animation the control belongs to, or "" to follow the selected animation.
+	namespace string
 }
 
 func NewControl() Control {
-	return Control{
-		Vars:   make(map[string]float64),
-		Colors: make(map[string]string),
-		mu:     &sync.Mutex{}}
+	return Control{store: NewStore()}
+}
+
+// Namespace returns a control for the animation's params, as each animation is given when it's built.
+func (c Control) Namespace(animation string) Control {
+	c.namespace = animation
+	return c
+}
+
+func (c *Control) Store() *Store {
+	return c.store
+}
+
+// namespaces returns the animation's namespace and GlobalNamespace, in the order names are looked up.
+func (c *Control) namespaces() []string {
+	ns := c.namespace
+	if ns == "" {
+		ns = Current()
+	}
+	return []string{ns, GlobalNamespace}
+}
+
+// resolve returns the namespace and param the name refers to.
+func (c *Control) resolve(name string) (string, Param, bool) {
+	for _, ns := range c.namespaces() {
+		if p, ok := declared(ns, name); ok {
+			return ns, p, true
+		}
+	}
+	return "", Param{}, false
 }
 
-func (c *Control) State() string {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	jsonBytes, _ := json.Marshal(c)
-	return string(jsonBytes)
+// Get returns the param's value, or nil if there's no param with that name.
+func (c *Control) Get(name string) interface{} {
+	ns, _, ok := c.resolve(name)
+	if !ok {
+		return nil
+	}
+	v, _ := c.store.Get(ns, name)
+	return v
 }
 
-// Load values from json
-func (c *Control) Load(jsonString string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	json.Unmarshal([]byte(jsonString), c)
+// Set validates and sets the param.
+func (c *Control) Set(name string, v interface{}) error {
+	ns, _, ok := c.resolve(name)
+	if !ok {
+		return fmt.Errorf("%s: %v", name, ErrUnknownParam)
+	}
+	return c.update([]paramValue{{ns, name, v}})
 }
 
+// SetJSON validates and sets a param in any namespace from its JSON value, as the store API does.
+func (c *Control) SetJSON(namespace, name string, raw json.RawMessage) error {
+	p, ok := declared(namespace, name)
+	if !ok {
+		return fmt.Errorf("%s.%s: %v", namespace, name, ErrUnknownParam)
+	}
+	v, err := p.decode(raw)
+	if err != nil {
+		return err
+	}
+	return c.update([]paramValue{{namespace, name, v}})
+}
+
+func (c *Control) update(values []paramValue) error {
+	changed, err := c.store.update(values)
+	if err != nil {
+		return err
+	}
+	for _, p := range changed {
+		if p.Type == ParamColor {
+			colorChanges.Inc(1)
+		} else {
+			varChanges.Inc(1)
+		}
+	}
+	return nil
+}
+
+// GetVar returns the number or int param, moved by its modulator if it has one.
 func (c *Control) GetVar(key string) float64 {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.Vars[key]
+	ns, p, ok := c.resolve(key)
+	if !ok {
+		return 0
+	}
+	v, _ := c.store.Get(ns, key)
+	val, _ := toFloat(v)
+	if m, ok := c.store.modulator(ns, key, false); ok {
+		return m.modulate(p.Min, p.Max, val, time.Since(modulatorEpoch).Seconds())
+	}
+	return val
+}
+
+func (c *Control) SetVar(key string, val float64) error {
+	return c.Set(key, val)
+}
+
+// DefaultVar sets the var only if it hasn't been set, e.g. restored from disk.
+func (c *Control) DefaultVar(key string, val float64) error {
+	ns, _, ok := c.resolve(key)
+	if !ok {
+		return fmt.Errorf("%s: %v", key, ErrUnknownParam)
+	}
+	if c.store.IsSet(ns, key) {
+		return nil
+	}
+	return c.update([]paramValue{{ns, key, val}})
+}
+
+func (c *Control) GetInt(key string) int {
+	i, _ := c.Get(key).(int)
+	return i
+}
+
+func (c *Control) GetBool(key string) bool {
+	b, _ := c.Get(key).(bool)
+	return b
+}
+
+// GetEnum returns which of the param's options is selected.
+func (c *Control) GetEnum(key string) string {
+	s, _ := c.Get(key).(string)
+	return s
 }
 
-func (c *Control) SetVar(key string, val float64) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.Vars[key] = val
+func (c *Control) GetGradient(key string) []GradientStop {
+	stops, _ := c.Get(key).([]GradientStop)
+	return stops
 }
 
+func (c *Control) GetPoint(key string) Point {
+	p, _ := c.Get(key).(Point)
+	return p
+}
+
+// GetColor returns the color, with its hue moved by its modulator if it has one.
 func (c *Control) GetColor(colorVar string) colorful.Color {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	hex := "#" + c.Colors[colorVar]
+	ns, _, ok := c.resolve(colorVar)
+	if !ok {
+		return colorful.Color{}
+	}
+	v, _ := c.store.Get(ns, colorVar)
+	hex := "#" + v.(string)
 	color, err := colorful.Hex(hex)
 	if err != nil {
 		log.Printf("Got error when parsing color: %s %v", hex, err)
 	}
+	if m, ok := c.store.modulator(ns, colorVar, true); ok {
+		return m.modulateHue(color, time.Since(modulatorEpoch).Seconds())
+	}
 	return color
 }
 
-func (c *Control) SetColor(colorVar string, color colorful.Color) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.Colors[colorVar] = strings.TrimLeft(color.Hex(), "#")
+func (c *Control) SetColor(colorVar string, color colorful.Color) error {
+	return c.Set(colorVar, strings.TrimLeft(color.Hex(), "#"))
 }
 
-//Expects a 6 digit hex color without the leading #
-func (c *Control) SetColorHex(colorVar string, color string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.Colors[colorVar] = color
+// Expects a 6 digit hex color, with or without the leading #
+func (c *Control) SetColorHex(colorVar string, color string) error {
+	return c.Set(colorVar, color)
 }
 
-//Returns 6 digit hex color without the leading #
+// Returns 6 digit hex color without the leading #
 func (c *Control) GetColorHex(colorVar string) string {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.Colors[colorVar]
+	s, _ := c.Get(colorVar).(string)
+	return s
+}
+
+// HasVar reports whether there's a number param with the name.
+func (c *Control) HasVar(key string) bool {
+	_, p, ok := c.resolve(key)
+	return ok && p.Type == ParamNumber
+}
+
+// HasColor reports whether there's a color param with the name.
+func (c *Control) HasColor(colorVar string) bool {
+	_, p, ok := c.resolve(colorVar)
+	return ok && p.Type == ParamColor
+}
+
+// CheckColorHex returns the color as 6 lower case hex digits without the leading #, which is
+// how colors are stored, or an error if it isn't a hex color.
+func CheckColorHex(colorVar string, color string) (string, error) {
+	hex := strings.TrimPrefix(color, "#")
+	if !colorHexPattern.MatchString(hex) {
+		return "", fmt.Errorf("%s must be a 6 digit hex color like ff00ff, got %q", colorVar, color)
+	}
+	return strings.ToLower(hex), nil
+}
+
+// Values returns every number and color param, including those still at their default.
+func (c *Control) Values() (map[string]float64, map[string]string) {
+	return c.values(c.namespaces())
+}
+
+// values returns every number and color param in the namespaces. Where two have a param with the
+// same name, the first namespace's wins.
+func (c *Control) values(namespaces []string) (map[string]float64, map[string]string) {
+	vars := make(map[string]float64)
+	colors := make(map[string]string)
+	// Global first, so the animation's params win.
+	for i := len(namespaces) - 1; i >= 0; i-- {
+		values := c.store.Values(namespaces[i])
+		for name, v := range values {
+			switch v := v.(type) {
+			case float64:
+				vars[name] = v
+			case string:
+				if p, _ := declared(namespaces[i], name); p.Type == ParamColor {
+					colors[name] = v
+				}
+			}
+		}
+	}
+	return vars, colors
+}
+
+// check returns an error unless there's a param of the type with the name that accepts the value.
+func (c *Control) check(name, paramType string, v interface{}) error {
+	_, p, ok := c.resolve(name)
+	if !ok || p.Type != paramType {
+		return fmt.Errorf("%s: %v", name, ErrUnknownParam)
+	}
+	_, err := p.check(v)
+	return err
+}
+
+// Update sets several vars and colors at once, so a frame never sees half of the change.
+// Nothing is set if any of them is invalid.
+func (c *Control) Update(vars map[string]float64, colors map[string]string) error {
+	values := make([]paramValue, 0, len(vars)+len(colors))
+	for name, v := range vars {
+		ns, p, ok := c.resolve(name)
+		if !ok || p.Type != ParamNumber {
+			return fmt.Errorf("no var named %s", name)
+		}
+		values = append(values, paramValue{ns, name, v})
+	}
+	for name, v := range colors {
+		ns, p, ok := c.resolve(name)
+		if !ok || p.Type != ParamColor {
+			return fmt.Errorf("no color named %s", name)
+		}
+		values = append(values, paramValue{ns, name, v})
+	}
+	return c.update(values)
 }
@@ -1,17 +1,45 @@
 package animation
 
 import (
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
 func TestJsonRoundTrip(t *testing.T) {
 	jsonString := `
-	{"Vars":{"A":500,"B":500,"C":500,"D":500},"Colors":{"A":"5c0d5c","B":"00ff00","C":"0000ff","D":"000000"}}
+	{"params": {
+		"global": {"brightness": 0.35, "dither": 1},
+		"opensimplex": {"speed": 0.1234567890123, "A": "5c0d5c", "varB": 0.5},
+		"retired-animation": {"A": 500, "mode": "spiral"}
+	}}
 	`
 
-	var c Control
-	c.Load(jsonString)
-	actualJson := c.State()
-	assert.JSONEq(t, jsonString, actualJson)
+	c := NewControl()
+	assert.NoError(t, json.Unmarshal([]byte(jsonString), c.Store()))
+	simplex := c.Namespace("opensimplex")
+	assert.Equal(t, 0.1234567890123, simplex.GetVar("speed"))
+	assert.Equal(t, "5c0d5c", simplex.GetColorHex("A"))
+	assert.Equal(t, 0.9, simplex.GetVar("varC"), "unset params read as their default")
+	actualJson, err := json.Marshal(c.Store())
+	assert.NoError(t, err)
+	assert.JSONEq(t, jsonString, string(actualJson))
+}
+
+func TestNamespaces(t *testing.T) {
+	c := NewControl()
+	simplex := c.Namespace("opensimplex")
+	gradient := c.Namespace("gradient-test")
+	assert.NoError(t, simplex.SetColorHex("A", "#FF0000"))
+	assert.NoError(t, gradient.SetColorHex("A", "00ff00"))
+	assert.NoError(t, gradient.SetVar("brightness", 0.5))
+
+	assert.Equal(t, "ff0000", simplex.GetColorHex("A"))
+	assert.Equal(t, "00ff00", gradient.GetColorHex("A"))
+	assert.Equal(t, 0.5, simplex.GetVar("brightness"), "globals are shared")
+
+	assert.Error(t, gradient.SetVar("speed", 0.5), "gradient-test has no speed")
+	assert.Error(t, simplex.SetVar("speed", 2))
+	assert.Error(t, simplex.Update(map[string]float64{"speed": 0.5}, map[string]string{"A": "red"}))
+	assert.Equal(t, 0.3, simplex.GetVar("speed"), "nothing is set when any value is invalid")
 }
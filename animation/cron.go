@@ -0,0 +1,110 @@
+package animation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cron is a parsed cron expression: minute, hour, day of month, month and day of week.
+// Each field is a bitmask of the values it matches.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// As in cron, when both days are restricted a time matches if either does.
+	domAny, dowAny bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	// Sunday is 0 or 7.
+	{"day of week", 0, 7},
+}
+
+// parseCron parses five space separated fields, each *, a number, a range like 1-5 or a list of
+// those like 1,3,5, optionally with a step like */15 or 9-17/2.
+func parseCron(expr string) (cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return cron{}, fmt.Errorf("cron %q needs 5 fields: minute hour day-of-month month day-of-week", expr)
+	}
+	var masks [5]uint64
+	for i, field := range fields {
+		mask, err := cronFields[i].parse(field)
+		if err != nil {
+			return cron{}, fmt.Errorf("cron %q: %v", expr, err)
+		}
+		masks[i] = mask
+	}
+	// Fold Sunday as 7 into 0.
+	if masks[4]&(1<<7) != 0 {
+		masks[4] |= 1
+	}
+	return cron{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    masks[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(field, ",") {
+		span, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			span = part[:i]
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %s %q", f.name, part)
+			}
+		}
+		lo, hi := f.min, f.max
+		if span != "*" {
+			var err error
+			bounds := strings.SplitN(span, "-", 2)
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, part)
+				}
+			}
+			if lo < f.min || hi > f.max || lo > hi {
+				return 0, fmt.Errorf("%s %q is outside %d-%d", f.name, part, f.min, f.max)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+func (c cron) matches(t time.Time) bool {
+	if c.minute&(1<<uint(t.Minute())) == 0 || c.hour&(1<<uint(t.Hour())) == 0 || c.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
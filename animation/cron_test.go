@@ -0,0 +1,33 @@
+package animation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCron(t *testing.T) {
+	weekdayEvenings, err := parseCron("*/15 18-22 * * 1-5")
+	assert.NoError(t, err)
+	// 2024-06-21 was a Friday.
+	assert.True(t, weekdayEvenings.matches(time.Date(2024, 6, 21, 18, 45, 0, 0, time.UTC)))
+	assert.False(t, weekdayEvenings.matches(time.Date(2024, 6, 21, 18, 50, 0, 0, time.UTC)))
+	assert.False(t, weekdayEvenings.matches(time.Date(2024, 6, 22, 18, 45, 0, 0, time.UTC)))
+
+	sundays, err := parseCron("0 9 * * 7")
+	assert.NoError(t, err)
+	assert.True(t, sundays.matches(time.Date(2024, 6, 23, 9, 0, 0, 0, time.UTC)))
+
+	// Either day matches when both are given.
+	firstOrMonday, err := parseCron("0 0 1 * 1")
+	assert.NoError(t, err)
+	assert.True(t, firstOrMonday.matches(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, firstOrMonday.matches(time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, firstOrMonday.matches(time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)))
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* 5-1 * * *", "*/0 * * * *", "* * 0 * *", "0 0 * * mon"} {
+		_, err := parseCron(bad)
+		assert.Error(t, err, bad)
+	}
+}
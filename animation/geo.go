@@ -4,12 +4,22 @@ import (
 	"github.com/StefanSchroeder/Golang-Ellipsoid/ellipsoid"
 	"github.com/golang/geo/s2"
 	"math"
+	"time"
 )
 
 const (
 	minVisibleLatitude = -48.75 //all points south of here don't have any pixels associated with them.
 	latitudeRange      = 90.0 + 48.75
 	epsilon            = 0.00001
+
+	// j2000 is the Julian date of 2000-01-01 12:00 UTC, and unixEpochJulian that of 1970-01-01 00:00 UTC.
+	j2000           = 2451545.0
+	unixEpochJulian = 2440587.5
+	// sunAltitude is where the sun's centre is at sunrise and sunset: half its disc below the horizon,
+	// plus the light bent over the horizon by the atmosphere.
+	sunAltitude = -0.833
+	// earthTilt is the obliquity of the ecliptic.
+	earthTilt = 23.4397
 )
 
 var (
@@ -48,3 +58,43 @@ func reverseBearing(bearing float64) float64 {
 	}
 	return bearing
 }
+
+// SunTimes returns when the sun rises and sets at lat, lon on the day of date, in date's location.
+// ok is false when the sun stays up or down all day, as it does near the poles.
+// The times are within a minute or two, following the sunrise equation.
+func SunTimes(date time.Time, lat, lon float64) (sunrise, sunset time.Time, ok bool) {
+	y, m, d := date.Date()
+	noon := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	days := math.Floor(julian(noon) - j2000 + 0.5)
+
+	meanNoon := days - lon/360
+	anomaly := math.Mod(357.5291+0.98560028*meanNoon, 360)
+	centre := 1.9148*sinDegrees(anomaly) + 0.02*sinDegrees(2*anomaly) + 0.0003*sinDegrees(3*anomaly)
+	longitude := math.Mod(anomaly+centre+180+102.9372, 360)
+	transit := j2000 + meanNoon + 0.0053*sinDegrees(anomaly) - 0.0069*sinDegrees(2*longitude)
+
+	declination := math.Asin(sinDegrees(longitude) * sinDegrees(earthTilt))
+	cosHourAngle := (sinDegrees(sunAltitude) - sinDegrees(lat)*math.Sin(declination)) / (cosDegrees(lat) * math.Cos(declination))
+	if cosHourAngle < -1 || cosHourAngle > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hourAngle := math.Acos(cosHourAngle) * 180 / math.Pi
+	loc := date.Location()
+	return fromJulian(transit - hourAngle/360).In(loc), fromJulian(transit + hourAngle/360).In(loc), true
+}
+
+func julian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + unixEpochJulian
+}
+
+func fromJulian(j float64) time.Time {
+	return time.Unix(int64((j-unixEpochJulian)*86400), 0)
+}
+
+func sinDegrees(degrees float64) float64 {
+	return math.Sin(degrees * math.Pi / 180)
+}
+
+func cosDegrees(degrees float64) float64 {
+	return math.Cos(degrees * math.Pi / 180)
+}
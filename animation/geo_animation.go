@@ -31,6 +31,7 @@ func NewGeoAnimation(control Control) Animation {
 		control: control,
 		pixels:  []*Pixel{pixels.getRandomPixel()},
 	}
+	bubbles = bubbles[:0]
 	for i := 0; i < 7; i++ {
 		bubbles = append(bubbles, newBubble(0))
 	}
@@ -109,5 +110,4 @@ func (a *GeoAnimation) frame(elapsed time.Duration, frameCount int) {
 			}
 		}
 	}
-	time.Sleep(40 * time.Millisecond)
 }
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/golang/geo/s2"
 	"github.com/stretchr/testify/assert"
+	"time"
 )
 
 //earth circum: 40,075 km
@@ -76,3 +77,14 @@ func TestReverseBearing(t *testing.T) {
 	assert.Equal(t, 270.0, reverseBearing(90.0))
 
 }
+
+func TestSunTimes(t *testing.T) {
+	pacific := time.FixedZone("PDT", -7*60*60)
+	sunrise, sunset, ok := SunTimes(time.Date(2024, 6, 21, 9, 0, 0, 0, pacific), 37.7749, -122.4194)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Date(2024, 6, 21, 5, 48, 0, 0, pacific), sunrise, 3*time.Minute)
+	assert.WithinDuration(t, time.Date(2024, 6, 21, 20, 35, 0, 0, pacific), sunset, 3*time.Minute)
+
+	_, _, ok = SunTimes(time.Date(2024, 6, 21, 12, 0, 0, 0, time.UTC), 78.2, 15.6)
+	assert.False(t, ok, "the sun doesn't set in Svalbard in June")
+}
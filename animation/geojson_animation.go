@@ -21,21 +21,6 @@ type GeojsonAnimation struct {
 }
 
 func NewGeojsonAnimation(control Control) *GeojsonAnimation {
-	colorA := colorful.Hsv(0.0, 1.0, 0.3) // red
-	colorB := colorful.Hsv(0.0, 1.0, 0.0)
-	colorC := colorful.Hsv(234.0, 0.0, 0.0)
-	colorD := colorful.Hsv(234.0, 1.0, 0.3) // purple
-
-	control.SetColor("A", colorA)
-	control.SetColor("B", colorB)
-	control.SetColor("C", colorC)
-	control.SetColor("D", colorD)
-
-	control.SetVar("A", 0.0)
-	control.SetVar("B", 0.1)
-	control.SetVar("C", 0.9)
-	control.SetVar("D", 1.0)
-
 	bytes, err := ioutil.ReadFile("animation/custom.geo.json")
 	if err != nil {
 		panic(err)
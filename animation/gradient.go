@@ -4,6 +4,19 @@ import "github.com/lucasb-eyer/go-colorful"
 
 //from: https://github.com/lucasb-eyer/go-colorful/blob/master/doc/gradientgen/gradientgen.go
 
+// gradientParams are the four colors, and their positions, of the gradient used by
+// OpenSimplexAnimation and GradientTestAnimation.
+var gradientParams = []Param{
+	ColorParam("A", "Color 1", colorful.Hsv(0.0, 1.0, 0.3)), // red
+	NumberParam("varA", "Color 1 position", 0, 1, 0.0, ""),
+	ColorParam("B", "Color 2", colorful.Hsv(0.0, 1.0, 0.0)),
+	NumberParam("varB", "Color 2 position", 0, 1, 0.1, ""),
+	ColorParam("C", "Color 3", colorful.Hsv(234.0, 0.0, 0.0)),
+	NumberParam("varC", "Color 3 position", 0, 1, 0.9, ""),
+	ColorParam("D", "Color 4", colorful.Hsv(234.0, 1.0, 0.3)), // purple
+	NumberParam("varD", "Color 4 position", 0, 1, 1.0, ""),
+}
+
 // This table contains the "keypoints" of the colorgradient you want to generate.
 // The position of each keypoint has to live in the range [0,1]
 type GradientTable []struct {
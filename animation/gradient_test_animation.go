@@ -2,7 +2,6 @@ package animation
 
 import (
 	"github.com/golang/geo/s2"
-	"github.com/lucasb-eyer/go-colorful"
 	"time"
 )
 
@@ -10,36 +9,26 @@ type GradientTestAnimation struct {
 	control  Control
 	gradient GradientTable
 	lat      float64
+	synced   syncedVersion
 }
 
 func NewGradientTestAnimation(control Control) *GradientTestAnimation {
-	colorA := colorful.Hsv(0.0, 1.0, 0.3) // red
-	colorB := colorful.Hsv(0.0, 1.0, 0.0)
-	colorC := colorful.Hsv(234.0, 0.0, 0.0)
-	colorD := colorful.Hsv(234.0, 1.0, 0.3) // purple
-
-	control.SetColor("A", colorA)
-	control.SetColor("B", colorB)
-	control.SetColor("C", colorC)
-	control.SetColor("D", colorD)
-
-	control.SetVar("A", 0.0)
-	control.SetVar("B", 0.1)
-	control.SetVar("C", 0.9)
-	control.SetVar("D", 1.0)
-
 	return &GradientTestAnimation{
 		control: control,
 		lat:     -90.0,
 	}
 }
 
+// syncControl reads the gradient again if it might have changed since the last frame.
 func (a *GradientTestAnimation) syncControl() {
+	if !a.synced.stale(&a.control) {
+		return
+	}
 	a.gradient = GradientTable{
-		{a.control.GetColor("A"), a.control.GetVar("A")},
-		{a.control.GetColor("B"), a.control.GetVar("B")},
-		{a.control.GetColor("C"), a.control.GetVar("C")},
-		{a.control.GetColor("D"), a.control.GetVar("D")},
+		{a.control.GetColor("A"), a.control.GetVar("varA")},
+		{a.control.GetColor("B"), a.control.GetVar("varB")},
+		{a.control.GetColor("C"), a.control.GetVar("varC")},
+		{a.control.GetColor("D"), a.control.GetVar("varD")},
 	}
 }
 
@@ -0,0 +1,285 @@
+package animation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/ojrac/opensimplex-go"
+)
+
+const (
+	ShapeSine     = "sine"
+	ShapeTriangle = "triangle"
+	ShapeSaw      = "saw"
+	// ShapeRandom wanders smoothly and unpredictably, at roughly Rate changes of direction a second.
+	ShapeRandom = "random"
+	// ShapeADSR is an envelope retriggered every cycle, with the gate held for the first half of it.
+	ShapeADSR = "adsr"
+
+	// randomScale stretches simplex noise, which stays within about ±0.87, to ±1.
+	randomScale = 1.15
+)
+
+var (
+	// modulatorEpoch is the clock every modulator runs on, so modulators with the same rate stay in step.
+	modulatorEpoch = time.Now()
+	walk           = opensimplex.NewWithSeed(modulatorEpoch.UnixNano())
+)
+
+// Modulator moves a var, or a color's hue, away from the value it's set to and back.
+type Modulator struct {
+	Shape string `json:"shape"`
+	// Rate is cycles a second.
+	Rate float64 `json:"rate"`
+	// Depth is how far the value moves, as a fraction of the var's range or of the color wheel.
+	// LFOs move either side of the value and envelopes above it.
+	Depth float64 `json:"depth"`
+	// Phase offsets the cycle, from 0 to 1. Random modulators with different phases wander differently.
+	Phase float64 `json:"phase"`
+
+	// Attack, Decay and Release are in seconds, Sustain is a level from 0 to 1. They're only used by envelopes.
+	Attack  float64 `json:"attack,omitempty"`
+	Decay   float64 `json:"decay,omitempty"`
+	Sustain float64 `json:"sustain,omitempty"`
+	Release float64 `json:"release,omitempty"`
+}
+
+func (m Modulator) Check() error {
+	switch m.Shape {
+	case ShapeSine, ShapeTriangle, ShapeSaw, ShapeRandom, ShapeADSR:
+	default:
+		return fmt.Errorf("unknown shape %q, use sine, triangle, saw, random or adsr", m.Shape)
+	}
+	if !(m.Rate > 0) || math.IsInf(m.Rate, 1) {
+		return errors.New("rate must be more than 0")
+	}
+	for _, v := range []float64{m.Depth, m.Phase, m.Sustain} {
+		if math.IsNaN(v) || v < 0 || v > 1 {
+			return errors.New("depth, phase and sustain must be between 0 and 1")
+		}
+	}
+	for _, v := range []float64{m.Attack, m.Decay, m.Release} {
+		if math.IsNaN(v) || v < 0 {
+			return errors.New("attack, decay and release must not be negative")
+		}
+	}
+	return nil
+}
+
+// value returns where the modulator is after the given number of seconds: from -1 to 1 for LFOs,
+// and 0 to 1 for envelopes.
+func (m Modulator) value(seconds float64) float64 {
+	x := seconds*m.Rate + m.Phase
+	cycle := x - math.Floor(x)
+	switch m.Shape {
+	case ShapeSine:
+		return math.Sin(2 * math.Pi * cycle)
+	case ShapeTriangle:
+		// Like the sine, start at 0 heading up.
+		return 4*math.Abs(math.Mod(cycle+0.75, 1)-0.5) - 1
+	case ShapeSaw:
+		return 2*math.Mod(cycle+0.5, 1) - 1
+	case ShapeRandom:
+		return math.Max(-1, math.Min(1, walk.Eval2(seconds*m.Rate, m.Phase*100)*randomScale))
+	case ShapeADSR:
+		return m.envelope(cycle / m.Rate)
+	}
+	return 0
+}
+
+// envelope returns the level the given number of seconds into a cycle.
+func (m Modulator) envelope(t float64) float64 {
+	gate := 0.5 / m.Rate
+	if t < gate {
+		return m.held(t)
+	}
+	if m.Release == 0 {
+		return 0
+	}
+	return m.held(gate) * math.Max(0, 1-(t-gate)/m.Release)
+}
+
+// held returns the level while the gate is held: rising, falling to the sustain level, then staying there.
+func (m Modulator) held(t float64) float64 {
+	if t < m.Attack {
+		return t / m.Attack
+	}
+	t -= m.Attack
+	if t < m.Decay {
+		return 1 - (1-m.Sustain)*t/m.Decay
+	}
+	return m.Sustain
+}
+
+// modulate returns the var moved by the modulator, kept within the var's range.
+func (m Modulator) modulate(min, max, val float64, seconds float64) float64 {
+	val += m.Depth * m.value(seconds) * (max - min)
+	return math.Max(min, math.Min(max, val))
+}
+
+// modulateHue returns the color with its hue moved by the modulator.
+func (m Modulator) modulateHue(color colorful.Color, seconds float64) colorful.Color {
+	h, s, v := color.Hsv()
+	h = math.Mod(h+m.Depth*m.value(seconds)*360, 360)
+	if h < 0 {
+		h += 360
+	}
+	return colorful.Hsv(h, s, v)
+}
+
+// SetModulator attaches the modulator to the number param, replacing any it had.
+func (c *Control) SetModulator(key string, m Modulator) error {
+	return c.setModulator(key, ParamNumber, &m)
+}
+
+// SetHueModulator attaches the modulator to the color's hue, replacing any it had.
+func (c *Control) SetHueModulator(colorVar string, m Modulator) error {
+	return c.setModulator(colorVar, ParamColor, &m)
+}
+
+func (c *Control) RemoveModulator(key string) error {
+	return c.setModulator(key, ParamNumber, nil)
+}
+
+func (c *Control) RemoveHueModulator(colorVar string) error {
+	return c.setModulator(colorVar, ParamColor, nil)
+}
+
+func (c *Control) setModulator(name, paramType string, m *Modulator) error {
+	ns, p, ok := c.resolve(name)
+	if !ok || p.Type != paramType {
+		return fmt.Errorf("%s: %v", name, ErrUnknownParam)
+	}
+	if m != nil {
+		if err := m.Check(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	c.store.setModulator(ns, name, paramType == ParamColor, m)
+	return nil
+}
+
+// checkModulator returns an error unless there's a param of the type with the name in one of the
+// namespaces and the modulator is valid.
+func (c *Control) checkModulator(namespaces []string, name, paramType string, m Modulator) error {
+	ns, p, ok := c.resolve(name)
+	if !ok || p.Type != paramType || !contains(namespaces, ns) {
+		return fmt.Errorf("%s: %v", name, ErrUnknownParam)
+	}
+	if err := m.Check(); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// ReplaceModulators swaps the modulators of the animation's and the global params for the given ones.
+// Nothing changes if any of them is invalid or is for a var or color that doesn't exist.
+func (c *Control) ReplaceModulators(vars, hues map[string]Modulator) error {
+	return c.replaceModulators(c.namespaces(), vars, hues)
+}
+
+// replaceModulators is ReplaceModulators for just the given namespaces.
+func (c *Control) replaceModulators(namespaces []string, vars, hues map[string]Modulator) error {
+	for name, m := range vars {
+		if err := c.checkModulator(namespaces, name, ParamNumber, m); err != nil {
+			return err
+		}
+	}
+	for name, m := range hues {
+		if err := c.checkModulator(namespaces, name, ParamColor, m); err != nil {
+			return err
+		}
+	}
+	c.store.mu.Lock()
+	for _, ns := range namespaces {
+		delete(c.store.modulators, ns)
+		delete(c.store.hueModulators, ns)
+		c.store.changed(ns, "")
+	}
+	c.store.mu.Unlock()
+	for name, m := range vars {
+		c.SetModulator(name, m)
+	}
+	for name, m := range hues {
+		c.SetHueModulator(name, m)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Modulating reports whether any of the animation's or the global params has a modulator, in which
+// case its values move without the Version changing.
+func (c *Control) Modulating() bool {
+	namespaces := c.namespaces()
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	for _, ns := range namespaces {
+		if len(c.store.modulators[ns]) > 0 || len(c.store.hueModulators[ns]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AllModulators returns a copy of the modulators of the animation's and the global params.
+func (c *Control) AllModulators() (map[string]Modulator, map[string]Modulator) {
+	return c.modulators(c.namespaces())
+}
+
+// modulators returns a copy of the modulators in the namespaces, the first namespace's winning.
+func (c *Control) modulators(namespaces []string) (map[string]Modulator, map[string]Modulator) {
+	vars := make(map[string]Modulator)
+	hues := make(map[string]Modulator)
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	for i := len(namespaces) - 1; i >= 0; i-- {
+		for name, m := range c.store.modulators[namespaces[i]] {
+			vars[name] = m
+		}
+		for name, m := range c.store.hueModulators[namespaces[i]] {
+			hues[name] = m
+		}
+	}
+	return vars, hues
+}
+
+func (s *Store) modulator(namespace, name string, hue bool) (Modulator, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	modulators := s.modulators
+	if hue {
+		modulators = s.hueModulators
+	}
+	m, ok := modulators[namespace][name]
+	return m, ok
+}
+
+// setModulator attaches the modulator, or removes it if m is nil.
+func (s *Store) setModulator(namespace, name string, hue bool, m *Modulator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	modulators := s.modulators
+	if hue {
+		modulators = s.hueModulators
+	}
+	defer s.changed(namespace, name)
+	if m == nil {
+		delete(modulators[namespace], name)
+		return
+	}
+	if modulators[namespace] == nil {
+		modulators[namespace] = make(map[string]Modulator)
+	}
+	modulators[namespace][name] = *m
+}
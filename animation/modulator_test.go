@@ -0,0 +1,102 @@
+package animation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModulatorShapes(t *testing.T) {
+	for _, shape := range []string{ShapeSine, ShapeTriangle} {
+		m := Modulator{Shape: shape, Rate: 0.5}
+		assert.InDelta(t, 0, m.value(0), 1e-9, shape)
+		assert.InDelta(t, 1, m.value(0.5), 1e-9, shape)
+		assert.InDelta(t, -1, m.value(1.5), 1e-9, shape)
+	}
+	saw := Modulator{Shape: ShapeSaw, Rate: 1}
+	assert.InDelta(t, 0, saw.value(0), 1e-9)
+	assert.InDelta(t, 0.5, saw.value(0.25), 1e-9)
+	assert.InDelta(t, -0.5, saw.value(0.75), 1e-9)
+
+	shifted := Modulator{Shape: ShapeSine, Rate: 1, Phase: 0.25}
+	assert.InDelta(t, 1, shifted.value(0), 1e-9)
+
+	random := Modulator{Shape: ShapeRandom, Rate: 2}
+	for s := 0.0; s < 10; s += 0.01 {
+		assert.InDelta(t, 0, random.value(s), 1)
+	}
+}
+
+func TestModulatorEnvelope(t *testing.T) {
+	// Two second cycles, so the gate is held for a second.
+	m := Modulator{Shape: ShapeADSR, Rate: 0.5, Attack: 0.2, Decay: 0.2, Sustain: 0.5, Release: 0.5}
+	assert.NoError(t, m.Check())
+	assert.InDelta(t, 0.5, m.value(0.1), 1e-9)
+	assert.InDelta(t, 1, m.value(0.2), 1e-9)
+	assert.InDelta(t, 0.75, m.value(0.3), 1e-9)
+	assert.InDelta(t, 0.5, m.value(0.9), 1e-9)
+	assert.InDelta(t, 0.25, m.value(1.25), 1e-9)
+	assert.InDelta(t, 0, m.value(1.9), 1e-9)
+	assert.InDelta(t, 0.5, m.value(2.1), 1e-9)
+}
+
+func TestModulatedVars(t *testing.T) {
+	assert.Error(t, Modulator{Shape: "square", Rate: 1}.Check())
+	assert.Error(t, Modulator{Shape: ShapeSine}.Check())
+	assert.Error(t, Modulator{Shape: ShapeSine, Rate: 1, Depth: 2}.Check())
+
+	m := Modulator{Shape: ShapeSine, Rate: 0.5, Depth: 0.25}
+	assert.InDelta(t, 0.75, m.modulate(0, 1, 0.5, 0.5), 1e-9)
+	assert.Equal(t, 1.0, m.modulate(0, 1, 0.9, 0.5), "kept within the var's range")
+
+	red := colorful.Color{R: 1}
+	h, _, _ := m.modulateHue(red, 0.5).Hsv()
+	assert.InDelta(t, 90, h, 1e-6)
+	h, _, _ = m.modulateHue(red, 1.5).Hsv()
+	assert.InDelta(t, 270, h, 1e-6)
+
+	c := NewControl().Namespace("opensimplex")
+	assert.NoError(t, c.SetVar("speed", 0.5))
+	assert.NoError(t, c.SetModulator("speed", Modulator{Shape: ShapeRandom, Rate: 50, Depth: 0.5}))
+	assert.Error(t, c.SetModulator("A", Modulator{Shape: ShapeRandom, Rate: 50, Depth: 0.5}), "A is a color")
+	vars, _ := c.Values()
+	assert.Equal(t, 0.5, vars["speed"], "the set value isn't changed")
+	moved := false
+	for i := 0; i < 1000 && !moved; i++ {
+		moved = c.GetVar("speed") != 0.5
+	}
+	assert.True(t, moved)
+
+	p := c.Snapshot("wobble")
+	c.RemoveModulator("speed")
+	assert.Equal(t, 0.5, c.GetVar("speed"))
+	c.Recall(p)
+	modulators, _ := c.AllModulators()
+	assert.Equal(t, ShapeRandom, modulators["speed"].Shape)
+}
+
+func TestInvalidModulatorsAreRejected(t *testing.T) {
+	c := NewControl().Namespace("opensimplex")
+	assert.Error(t, c.SetModulator("speed", Modulator{Shape: ShapeADSR}), "a rate of 0")
+	assert.Error(t, c.SetHueModulator("A", Modulator{Shape: "square", Rate: 1}))
+	assert.Error(t, c.ReplaceModulators(map[string]Modulator{"speed": {Shape: ShapeSine, Rate: 1, Depth: 2}}, nil))
+
+	assert.NoError(t, c.SetModulator("varA", Modulator{Shape: ShapeSine, Rate: 1}))
+	c.Recall(Preset{Name: "edited", Modulators: map[string]Modulator{
+		"speed": {Shape: ShapeSaw, Rate: 1},
+		"varB":  {Shape: ShapeSine, Rate: -1},
+	}})
+	modulators, _ := c.AllModulators()
+	assert.Len(t, modulators, 1, "the bad modulator is skipped")
+	assert.Equal(t, ShapeSaw, modulators["speed"].Shape)
+
+	saved := `{"params": {}, "modulators": {"opensimplex": {"speed": {"shape": "sine", "rate": 0, "depth": 0.1, "phase": 0},
+		"varA": {"shape": "sine", "rate": 1, "depth": 0.1, "phase": 0}}}}`
+	restored := NewControl().Namespace("opensimplex")
+	assert.NoError(t, json.Unmarshal([]byte(saved), restored.Store()))
+	modulators, _ = restored.AllModulators()
+	assert.Len(t, modulators, 1)
+	assert.Contains(t, modulators, "varA")
+}
@@ -9,7 +9,6 @@ import (
 	"sync"
 
 	"github.com/launchdarkly/go-metrics"
-	"github.com/lucasb-eyer/go-colorful"
 	"github.com/ojrac/opensimplex-go"
 )
 
@@ -21,24 +20,14 @@ type OpenSimplexAnimation struct {
 	histo    metrics.Histogram
 	min      float64
 	max      float64
+	synced   syncedVersion
 }
 
-func NewOpenSimplexAnimation(control Control) *OpenSimplexAnimation {
-	colorA := colorful.Hsv(0.0, 1.0, 0.3) // red
-	colorB := colorful.Hsv(0.0, 1.0, 0.0)
-	colorC := colorful.Hsv(234.0, 0.0, 0.0)
-	colorD := colorful.Hsv(234.0, 1.0, 0.3) // purple
-
-	control.SetColor("A", colorA)
-	control.SetColor("B", colorB)
-	control.SetColor("C", colorC)
-	control.SetColor("D", colorD)
-
-	control.SetVar("varA", 0.0)
-	control.SetVar("varB", 0.1)
-	control.SetVar("varC", 0.9)
-	control.SetVar("varD", 1.0)
+var openSimplexParams = append([]Param{
+	NumberParam("speed", "Speed", 0, 1, 0.3, ""),
+}, gradientParams...)
 
+func NewOpenSimplexAnimation(control Control) *OpenSimplexAnimation {
 	return &OpenSimplexAnimation{
 		control: control,
 		noise:   opensimplex.NewWithSeed(time.Now().UnixNano()),
@@ -46,7 +35,11 @@ func NewOpenSimplexAnimation(control Control) *OpenSimplexAnimation {
 	}
 }
 
+// syncControl reads the gradient and speed again if they might have changed since the last frame.
 func (a *OpenSimplexAnimation) syncControl() {
+	if !a.synced.stale(&a.control) {
+		return
+	}
 	a.gradient = GradientTable{
 		{a.control.GetColor("A"), a.control.GetVar("varA")},
 		{a.control.GetColor("B"), a.control.GetVar("varB")},
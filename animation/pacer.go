@@ -0,0 +1,107 @@
+package animation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/go-metrics"
+)
+
+const (
+	DefaultFPS  = 30.0
+	statsWindow = 1 * time.Second
+)
+
+// FrameStats describes how well the render loop is keeping up with its target frame rate.
+type FrameStats struct {
+	TargetFPS float64 `json:"targetFps"`
+	// FPS is the rate measured over the last second.
+	FPS    float64 `json:"fps"`
+	Frames uint64  `json:"frames"`
+	// Late counts frames that took longer than one frame interval to generate.
+	Late uint64 `json:"late"`
+	// Dropped counts frames that an output replaced with a newer one before sending it.
+	Dropped uint64 `json:"dropped"`
+	// GenerateMillis is the average time spent generating a frame over the last second.
+	GenerateMillis float64 `json:"generateMillis"`
+}
+
+var (
+	generateTimer  = metrics.GetOrRegisterTimer("frame.generate", metrics.DefaultRegistry)
+	frameCounter   = metrics.GetOrRegisterCounter("frame.frames", metrics.DefaultRegistry)
+	lateCounter    = metrics.GetOrRegisterCounter("frame.late", metrics.DefaultRegistry)
+	droppedCounter = metrics.GetOrRegisterCounter("frame.dropped", metrics.DefaultRegistry)
+	fpsGauge       = metrics.GetOrRegisterGaugeFloat64("frame.fps", metrics.DefaultRegistry)
+)
+
+// pacer starts a frame every interval. A frame that runs over pushes the schedule back
+// rather than being followed by a burst of frames to catch up.
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+
+	mu             sync.Mutex
+	stats          FrameStats
+	windowStart    time.Time
+	windowFrames   int
+	windowGenerate time.Duration
+}
+
+func newPacer(fps float64, now time.Time) *pacer {
+	if fps <= 0 {
+		fps = DefaultFPS
+	}
+	return &pacer{
+		interval:    time.Duration(float64(time.Second) / fps),
+		next:        now,
+		stats:       FrameStats{TargetFPS: fps},
+		windowStart: now,
+	}
+}
+
+// wait sleeps until the next frame is due.
+func (p *pacer) wait() {
+	if d := time.Until(p.next); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// generated records a frame that was generated between start and end, and schedules the next one.
+func (p *pacer) generated(start, end time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Frames++
+	p.windowFrames++
+	p.windowGenerate += end.Sub(start)
+	frameCounter.Inc(1)
+	generateTimer.Update(end.Sub(start))
+
+	p.next = p.next.Add(p.interval)
+	if end.After(p.next) {
+		p.stats.Late++
+		lateCounter.Inc(1)
+		p.next = end
+	}
+
+	if elapsed := end.Sub(p.windowStart); elapsed >= statsWindow {
+		p.stats.FPS = float64(p.windowFrames) / elapsed.Seconds()
+		p.stats.GenerateMillis = p.windowGenerate.Seconds() * 1000 / float64(p.windowFrames)
+		fpsGauge.Update(p.stats.FPS)
+		p.windowStart = end
+		p.windowFrames = 0
+		p.windowGenerate = 0
+	}
+}
+
+func (p *pacer) setDropped(dropped uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	droppedCounter.Inc(int64(dropped - p.stats.Dropped))
+	p.stats.Dropped = dropped
+}
+
+func (p *pacer) Stats() FrameStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
@@ -0,0 +1,42 @@
+package animation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacerSchedulesAtTargetFPS(t *testing.T) {
+	start := time.Now()
+	p := newPacer(50, start)
+	assert.Equal(t, 20*time.Millisecond, p.interval)
+
+	// The 51st frame ends just past the one second stats window.
+	for i := 0; i <= 50; i++ {
+		frameStart := start.Add(time.Duration(i) * p.interval)
+		p.generated(frameStart, frameStart.Add(5*time.Millisecond))
+	}
+	assert.Equal(t, start.Add(51*p.interval), p.next)
+	stats := p.Stats()
+	assert.Equal(t, uint64(51), stats.Frames)
+	assert.Equal(t, uint64(0), stats.Late)
+	assert.InDelta(t, 5.0, stats.GenerateMillis, 0.001)
+	assert.InDelta(t, 50.0, stats.FPS, 1.0)
+}
+
+func TestPacerLateFrameDelaysSchedule(t *testing.T) {
+	start := time.Now()
+	p := newPacer(50, start)
+
+	end := start.Add(70 * time.Millisecond)
+	p.generated(start, end)
+	assert.Equal(t, uint64(1), p.Stats().Late)
+	// The next frame starts right away instead of trying to make up the missed ones.
+	assert.Equal(t, end, p.next)
+}
+
+func TestPacerDefaultFPS(t *testing.T) {
+	p := newPacer(0, time.Now())
+	assert.Equal(t, DefaultFPS, p.Stats().TargetFPS)
+}
@@ -0,0 +1,76 @@
+package animation
+
+import (
+	"strings"
+
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	ParamNumber = "number"
+	ParamInt    = "int"
+	ParamBool   = "bool"
+	// ParamEnum is one of the param's Options.
+	ParamEnum  = "enum"
+	ParamColor = "color"
+	// ParamGradient is a list of GradientStops.
+	ParamGradient = "gradient"
+	// ParamPoint is a Point on the globe.
+	ParamPoint = "point"
+)
+
+// Param describes one value an animation reads, so it can be checked when it's set and a UI can be built for it.
+type Param struct {
+	// Name is the Control var or color name.
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	// Type is ParamNumber for vars and ParamColor for colors, or one of the other Param types.
+	Type string `json:"type"`
+	// Min and Max are the range of numbers and ints.
+	Min     float64  `json:"min"`
+	Max     float64  `json:"max"`
+	Options []string `json:"options,omitempty"`
+	// Default is a float64 for numbers, an int for ints, a 6 digit hex string for colors, and so on.
+	Default interface{} `json:"default"`
+	Unit    string      `json:"unit,omitempty"`
+}
+
+// GlobalParams apply to every animation.
+var GlobalParams = []Param{
+	NumberParam("brightness", "Brightness", 0, 1, 1.0, ""),
+	NumberParam("dither", "Dither", 0, 1, 0.0, ""),
+}
+
+func NumberParam(name, label string, min, max, def float64, unit string) Param {
+	return Param{Name: name, Label: label, Type: ParamNumber, Min: min, Max: max, Default: def, Unit: unit}
+}
+
+func IntParam(name, label string, min, max, def int, unit string) Param {
+	return Param{Name: name, Label: label, Type: ParamInt, Min: float64(min), Max: float64(max), Default: def, Unit: unit}
+}
+
+func BoolParam(name, label string, def bool) Param {
+	return Param{Name: name, Label: label, Type: ParamBool, Default: def}
+}
+
+func EnumParam(name, label string, options []string, def string) Param {
+	return Param{Name: name, Label: label, Type: ParamEnum, Options: options, Default: def}
+}
+
+func ColorParam(name, label string, def colorful.Color) Param {
+	return Param{Name: name, Label: label, Type: ParamColor, Default: strings.TrimLeft(def.Hex(), "#")}
+}
+
+func GradientParam(name, label string, def []GradientStop) Param {
+	return Param{Name: name, Label: label, Type: ParamGradient, Default: def}
+}
+
+func PointParam(name, label string, def Point) Param {
+	return Param{Name: name, Label: label, Type: ParamPoint, Default: def}
+}
+
+// Params returns the global params followed by those of the selected animation.
+func Params() []Param {
+	params := append([]Param{}, GlobalParams...)
+	return append(params, animations[Current()].params...)
+}
@@ -0,0 +1,38 @@
+package animation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultsFromParams(t *testing.T) {
+	c := NewControl().Namespace("gradient-test")
+	assert.NoError(t, c.SetVar("varB", 0.5))
+	assert.NoError(t, c.DefaultVar("varB", 0.2))
+	assert.NoError(t, c.DefaultVar("varA", 0.2))
+
+	assert.Equal(t, 0.5, c.GetVar("varB"), "set values are kept")
+	assert.Equal(t, 0.2, c.GetVar("varA"))
+	assert.Equal(t, 0.9, c.GetVar("varC"))
+	assert.Equal(t, "4d0000", c.GetColorHex("A"))
+}
+
+func TestEveryAnimationIsDescribed(t *testing.T) {
+	for _, name := range Names() {
+		info, err := Describe(name)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, info.Description, name)
+		for _, p := range info.Params {
+			assert.Contains(t, []string{ParamNumber, ParamColor}, p.Type, name+" "+p.Name)
+			assert.NotEmpty(t, p.Label, name+" "+p.Name)
+		}
+	}
+}
+
+func TestParamRangeIsAlwaysServed(t *testing.T) {
+	bytes, err := json.Marshal(NumberParam("speed", "Speed", 0, 1, 0.3, ""))
+	assert.NoError(t, err)
+	assert.Contains(t, string(bytes), `"min":0`, "the UI needs both ends of the range")
+}
@@ -0,0 +1,140 @@
+package animation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// LoadFile restores the params and modulators saved by SaveFile. A missing file isn't an error,
+// it just means nothing has been saved yet. Files saved before params had namespaces, with flat
+// "Vars" and "Colors", are applied to the selected animation.
+func (c *Control) LoadFile(path string) error {
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved struct {
+		Params json.RawMessage `json:"params"`
+		Vars   map[string]float64
+		Colors map[string]string
+	}
+	if err := json.Unmarshal(bytes, &saved); err != nil {
+		return fmt.Errorf("error reading %s: %v", path, err)
+	}
+	switch {
+	case saved.Params != nil:
+		if err := json.Unmarshal(bytes, c.store); err != nil {
+			return err
+		}
+	case saved.Vars != nil || saved.Colors != nil:
+		// Left unsaved, so Persist rewrites it in the current format.
+		c.recall(Preset{Vars: saved.Vars, Colors: saved.Colors}, c.namespaces())
+		return nil
+	default:
+		return fmt.Errorf("no params in %s", path)
+	}
+	atomic.StoreUint64(&c.store.saved, atomic.LoadUint64(&c.store.version))
+	return nil
+}
+
+// SaveFile writes the params and modulators to path. The state goes to a temporary file that is
+// renamed over path, so a crash or power cut never leaves a half written file behind.
+func (c *Control) SaveFile(path string) error {
+	version := atomic.LoadUint64(&c.store.version)
+	bytes, err := json.MarshalIndent(c.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, bytes); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&c.store.saved, version)
+	return nil
+}
+
+// writeFileAtomic replaces path with data so that readers see either the old or the new contents.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Persist saves the params and modulators to path whenever they change. Changes are collected for
+// the debounce interval first, so dragging a slider writes the file once rather than for every step.
+// Changes made since the last LoadFile or SaveFile, including those made before Persist was called,
+// are saved too. It returns once stop is closed, after saving anything still unsaved; a nil stop
+// keeps it running forever.
+func (c *Control) Persist(path string, debounce time.Duration, stop <-chan struct{}) {
+	changes, unsubscribe := c.SubscribeAll()
+	defer unsubscribe()
+	defer func() {
+		if c.unsaved() {
+			c.save(path)
+		}
+	}()
+	for {
+		// Selecting another animation wakes us too, but there's nothing to save for it.
+		for !c.unsaved() {
+			select {
+			case <-changes:
+			case <-stop:
+				return
+			}
+		}
+		select {
+		case <-time.After(debounce):
+		case <-stop:
+			return
+		}
+		// Anything that changed while we slept is included in this save.
+		select {
+		case <-changes:
+		default:
+		}
+		if !c.save(path) {
+			// Try again after the next change, rather than over and over.
+			select {
+			case <-changes:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// unsaved reports whether anything has changed since the last LoadFile or SaveFile.
+func (c *Control) unsaved() bool {
+	return atomic.LoadUint64(&c.store.version) != atomic.LoadUint64(&c.store.saved)
+}
+
+// save is SaveFile, logging rather than returning the error.
+func (c *Control) save(path string) bool {
+	if err := c.SaveFile(path); err != nil {
+		log.Printf("Error saving control state to %s: %v", path, err)
+		return false
+	}
+	return true
+}
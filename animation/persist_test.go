@@ -0,0 +1,125 @@
+package animation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	saved := NewControl().Namespace("opensimplex")
+	assert.NoError(t, saved.SetVar("speed", 0.7))
+	assert.NoError(t, saved.SetColorHex("A", "00ff00"))
+	assert.NoError(t, saved.SetModulator("varA", Modulator{Shape: ShapeSine, Rate: 1, Depth: 0.1}))
+	assert.NoError(t, saved.SaveFile(path))
+
+	restored := NewControl().Namespace("opensimplex")
+	assert.NoError(t, restored.LoadFile(path))
+	restored.DefaultVar("speed", 0.3)
+	restored.DefaultVar("brightness", 0.5)
+	assert.Equal(t, 0.7, restored.GetVar("speed"))
+	assert.Equal(t, 0.5, restored.GetVar("brightness"))
+	assert.Equal(t, "00ff00", restored.GetColorHex("A"))
+	modulators, _ := restored.AllModulators()
+	assert.Equal(t, ShapeSine, modulators["varA"].Shape)
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Len(t, files, 1, "the temporary file should be renamed into place")
+}
+
+func TestLoadFlatFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	flat := `{"Vars": {"speed": 0.6, "brightness": 0.4, "A": 500}, "Colors": {"B": "00ff00"}}`
+	assert.NoError(t, ioutil.WriteFile(path, []byte(flat), 0644))
+
+	c := NewControl().Namespace("opensimplex")
+	assert.NoError(t, c.LoadFile(path))
+	assert.Equal(t, 0.6, c.GetVar("speed"))
+	assert.Equal(t, 0.4, c.GetVar("brightness"))
+	assert.Equal(t, "00ff00", c.GetColorHex("B"))
+}
+
+func TestLoadFlatFileIntoSelectedAnimation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Vars": {"varB": 0.3}, "Colors": {"A": "00ff00"}}`), 0644))
+
+	defer Select(Current())
+	assert.NoError(t, Select("gradient-test"))
+	c := NewControl()
+	assert.NoError(t, c.LoadFile(path))
+	gradient, simplex := c.Namespace("gradient-test"), c.Namespace("opensimplex")
+	assert.Equal(t, 0.3, gradient.GetVar("varB"))
+	assert.Equal(t, "00ff00", gradient.GetColorHex("A"))
+	assert.Equal(t, "4d0000", simplex.GetColorHex("A"), "other animations keep their defaults")
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	c := NewControl()
+	assert.NoError(t, c.LoadFile(filepath.Join(os.TempDir(), "no-such-ledicious-state.json")))
+}
+
+func TestPersistSavesAfterChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	c := NewControl().Namespace("opensimplex")
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		c.Persist(path, 10*time.Millisecond, stop)
+		close(stopped)
+	}()
+	defer func() {
+		close(stop)
+		<-stopped
+	}()
+	c.SetVar("speed", 0.1)
+	c.SetVar("speed", 0.2)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		restored := NewControl().Namespace("opensimplex")
+		if restored.LoadFile(path) == nil && restored.GetVar("speed") == 0.2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("state was not saved")
+}
+
+func TestPersistSavesWhenStopped(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	c := NewControl().Namespace("opensimplex")
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		c.Persist(path, time.Hour, stop)
+		close(stopped)
+	}()
+	c.SetVar("speed", 0.3)
+	close(stop)
+	<-stopped
+
+	restored := NewControl().Namespace("opensimplex")
+	assert.NoError(t, restored.LoadFile(path))
+	assert.Equal(t, 0.3, restored.GetVar("speed"))
+}
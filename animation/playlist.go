@@ -0,0 +1,308 @@
+package animation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+const playlistTick = 100 * time.Millisecond
+
+var ErrPlaylistEmpty = errors.New("the playlist is empty")
+
+// PlaylistEntry shows an animation, keeping the vars and colors as they are, or recalls a preset, for Seconds.
+type PlaylistEntry struct {
+	Animation string  `json:"animation,omitempty"`
+	Preset    string  `json:"preset,omitempty"`
+	Seconds   float64 `json:"seconds"`
+}
+
+// PlaylistState is what's saved to disk, so the playlist carries on after a restart.
+type PlaylistState struct {
+	Entries []PlaylistEntry `json:"entries"`
+	Shuffle bool            `json:"shuffle"`
+	Playing bool            `json:"playing"`
+	// Position is how far through the play order the playlist is.
+	Position int `json:"position"`
+	// Order is the play order, as indexes into Entries. It's reshuffled each time round when Shuffle is set.
+	Order []int `json:"order"`
+}
+
+// PlaylistStatus is the state plus what's showing now.
+type PlaylistStatus struct {
+	PlaylistState
+	Current          *PlaylistEntry `json:"current,omitempty"`
+	SecondsRemaining float64        `json:"secondsRemaining"`
+}
+
+// Playlist cycles through animations and presets on its own while it's playing.
+type Playlist struct {
+	control Control
+	presets *PresetStore
+	path    string
+
+	mu    sync.Mutex
+	state PlaylistState
+	// deadline is when the current entry ends, and remaining how much of it was left when paused.
+	deadline  time.Time
+	remaining time.Duration
+}
+
+// NewPlaylist returns the playlist saved at path, or an empty one if nothing has been saved.
+// A playlist that was playing when it was saved carries on from the entry it was showing.
+func NewPlaylist(control Control, presets *PresetStore, path string) (*Playlist, error) {
+	p := &Playlist{control: control, presets: presets, path: path}
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(bytes, &p.state); err != nil {
+		p.state = PlaylistState{}
+		return p, fmt.Errorf("error reading playlist %s: %v", path, err)
+	}
+	// The file may have been edited by hand, so drop entries that can never play, and don't trust
+	// the order or position to fit the entries. Presets may have been deleted since the playlist was
+	// saved; those entries are kept and skipped when they come up, as they are while playing.
+	entries := make([]PlaylistEntry, 0, len(p.state.Entries))
+	for i, e := range p.state.Entries {
+		if err := p.checkEntry(e, false); err != nil {
+			log.Printf("Dropping entry %d of playlist %s: %v", i, path, err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != len(p.state.Entries) {
+		p.state.Entries, p.state.Order = entries, nil
+	}
+	if !p.validOrder() {
+		p.state.Order = p.order()
+		p.state.Position = 0
+	}
+	if p.state.Position < 0 || p.state.Position >= len(p.state.Order) {
+		p.state.Position = 0
+	}
+	if len(p.state.Entries) == 0 {
+		p.state.Playing = false
+	}
+	if p.state.Playing {
+		p.show(time.Now())
+	}
+	return p, nil
+}
+
+// CheckEntry returns an error unless the entry names exactly one animation or existing preset and lasts a while.
+func (p *Playlist) CheckEntry(e PlaylistEntry) error {
+	return p.checkEntry(e, true)
+}
+
+// checkEntry is CheckEntry, only checking that a preset exists if presets is true.
+func (p *Playlist) checkEntry(e PlaylistEntry, presets bool) error {
+	if e.Seconds <= 0 {
+		return errors.New("seconds must be more than 0")
+	}
+	switch {
+	case e.Animation != "" && e.Preset != "":
+		return errors.New("an entry is an animation or a preset, not both")
+	case e.Animation != "":
+		if _, ok := animations[e.Animation]; !ok {
+			return fmt.Errorf("%s: %v", e.Animation, ErrUnknownAnimation)
+		}
+	case e.Preset != "" && !presets:
+		return CheckPresetName(e.Preset)
+	case e.Preset != "":
+		if _, err := p.presets.Get(e.Preset); err != nil {
+			return fmt.Errorf("%s: %v", e.Preset, err)
+		}
+	default:
+		return errors.New("an entry needs an animation or a preset")
+	}
+	return nil
+}
+
+// Set replaces the entries and starts again from the first one.
+func (p *Playlist) Set(entries []PlaylistEntry, shuffle bool) error {
+	for _, e := range entries {
+		if err := p.CheckEntry(e); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state.Entries = entries
+	p.state.Shuffle = shuffle
+	p.state.Order = p.order()
+	p.state.Position = 0
+	if len(entries) == 0 {
+		p.state.Playing = false
+	}
+	if p.state.Playing {
+		p.show(time.Now())
+	}
+	p.remaining = 0
+	return p.save()
+}
+
+// Play starts the playlist, or carries on with the current entry if it was paused.
+func (p *Playlist) Play() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.state.Entries) == 0 {
+		return ErrPlaylistEmpty
+	}
+	if p.state.Playing {
+		return nil
+	}
+	p.state.Playing = true
+	if p.remaining > 0 {
+		p.deadline = time.Now().Add(p.remaining)
+		p.remaining = 0
+	} else {
+		p.show(time.Now())
+	}
+	return p.save()
+}
+
+// Pause stops the playlist on the current entry.
+func (p *Playlist) Pause() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.state.Playing {
+		return nil
+	}
+	p.state.Playing = false
+	p.remaining = time.Until(p.deadline)
+	return p.save()
+}
+
+// Skip moves on to the next entry straight away.
+func (p *Playlist) Skip() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.state.Entries) == 0 {
+		return ErrPlaylistEmpty
+	}
+	p.advance(time.Now())
+	p.remaining = 0
+	return p.save()
+}
+
+// Status returns the playlist and how long the current entry has left.
+func (p *Playlist) Status() PlaylistStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := PlaylistStatus{PlaylistState: p.state}
+	if len(p.state.Entries) > 0 {
+		e := p.current()
+		status.Current = &e
+		if p.state.Playing {
+			status.SecondsRemaining = time.Until(p.deadline).Seconds()
+		} else {
+			status.SecondsRemaining = p.remaining.Seconds()
+		}
+	}
+	return status
+}
+
+// Run moves the playlist on as each entry's time is up. It never returns.
+func (p *Playlist) Run() {
+	for now := range time.Tick(playlistTick) {
+		p.tick(now)
+	}
+}
+
+func (p *Playlist) tick(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.state.Playing || now.Before(p.deadline) {
+		return
+	}
+	p.advance(now)
+	if err := p.save(); err != nil {
+		log.Printf("Error saving playlist to %s: %v", p.path, err)
+	}
+}
+
+// advance shows the next entry, reshuffling at the end of the list. Entries that can't be shown,
+// e.g. a preset that's since been deleted, are skipped.
+// It must be called with the lock held, as must the methods below.
+func (p *Playlist) advance(now time.Time) {
+	for range p.state.Entries {
+		p.state.Position++
+		if p.state.Position >= len(p.state.Order) {
+			p.state.Position = 0
+			p.state.Order = p.order()
+		}
+		if p.show(now) {
+			return
+		}
+	}
+}
+
+// show switches to the current entry and returns whether that worked.
+func (p *Playlist) show(now time.Time) bool {
+	e := p.current()
+	p.deadline = now.Add(time.Duration(e.Seconds * float64(time.Second)))
+	if e.Animation != "" {
+		if err := Select(e.Animation); err != nil {
+			log.Printf("Error showing animation %s from the playlist: %v", e.Animation, err)
+			return false
+		}
+		return true
+	}
+	preset, err := p.presets.Get(e.Preset)
+	if err == nil {
+		err = p.control.Show(preset)
+	}
+	if err != nil {
+		log.Printf("Error showing preset %s from the playlist: %v", e.Preset, err)
+		return false
+	}
+	return true
+}
+
+func (p *Playlist) current() PlaylistEntry {
+	return p.state.Entries[p.state.Order[p.state.Position]]
+}
+
+// validOrder reports whether the order has every entry exactly once.
+func (p *Playlist) validOrder() bool {
+	if len(p.state.Order) != len(p.state.Entries) {
+		return false
+	}
+	seen := make([]bool, len(p.state.Entries))
+	for _, i := range p.state.Order {
+		if i < 0 || i >= len(seen) || seen[i] {
+			return false
+		}
+		seen[i] = true
+	}
+	return true
+}
+
+func (p *Playlist) order() []int {
+	if p.state.Shuffle {
+		return rand.Perm(len(p.state.Entries))
+	}
+	order := make([]int, len(p.state.Entries))
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+func (p *Playlist) save() error {
+	bytes, err := json.MarshalIndent(p.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.path, bytes)
+}
@@ -0,0 +1,116 @@
+package animation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlaylistAdvances(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewPresetStore(dir)
+	assert.NoError(t, err)
+
+	c := NewControl()
+	gradient := c.Namespace("gradient-test")
+	assert.NoError(t, gradient.SetVar("varB", 0.4))
+	calm := gradient.Snapshot("calm")
+	calm.Animation = "gradient-test"
+	assert.NoError(t, store.Save(calm))
+	assert.NoError(t, gradient.SetVar("varB", 0.9))
+
+	path := filepath.Join(dir, "playlist.json")
+	p, err := NewPlaylist(c, store, path)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrPlaylistEmpty, p.Play())
+	assert.Error(t, p.Set([]PlaylistEntry{{Animation: "nope", Seconds: 1}}, false))
+	assert.Error(t, p.Set([]PlaylistEntry{{Preset: "calm"}}, false))
+
+	assert.NoError(t, p.Set([]PlaylistEntry{{Animation: "brightness-test", Seconds: 10}, {Preset: "calm", Seconds: 5}}, false))
+	assert.NoError(t, p.Play())
+	assert.Equal(t, "brightness-test", Current())
+	assert.Equal(t, 0.9, gradient.GetVar("varB"))
+
+	p.tick(time.Now().Add(5 * time.Second))
+	assert.Equal(t, "brightness-test", Current())
+	p.tick(time.Now().Add(11 * time.Second))
+	assert.Equal(t, "gradient-test", Current())
+	assert.Equal(t, 0.4, gradient.GetVar("varB"))
+
+	assert.NoError(t, p.Skip())
+	assert.Equal(t, "brightness-test", Current())
+	assert.Equal(t, 0, p.Status().Position)
+
+	// A restart carries on from the same entry.
+	assert.NoError(t, p.Skip())
+	restored, err := NewPlaylist(c, store, path)
+	assert.NoError(t, err)
+	assert.True(t, restored.Status().Playing)
+	assert.Equal(t, "calm", restored.Status().Current.Preset)
+
+	assert.NoError(t, p.Pause())
+	p.tick(time.Now().Add(time.Minute))
+	assert.Equal(t, "calm", p.Status().Current.Preset)
+}
+
+func TestPlaylistSkipsMissingPresets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewPresetStore(dir)
+	assert.NoError(t, err)
+	c := NewControl()
+	assert.NoError(t, store.Save(c.Snapshot("gone")))
+
+	p, err := NewPlaylist(c, store, filepath.Join(dir, "playlist.json"))
+	assert.NoError(t, err)
+	entries := []PlaylistEntry{{Animation: "gradient-test", Seconds: 1}, {Preset: "gone", Seconds: 1}, {Animation: "brightness-test", Seconds: 1}}
+	assert.NoError(t, p.Set(entries, false))
+	assert.NoError(t, p.Play())
+	assert.NoError(t, store.Delete("gone"))
+
+	p.tick(time.Now().Add(2 * time.Second))
+	assert.Equal(t, "brightness-test", Current())
+	assert.Equal(t, 2, p.Status().Position)
+}
+
+func TestPlaylistRepairsEditedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewPresetStore(dir)
+	assert.NoError(t, err)
+	path := filepath.Join(dir, "playlist.json")
+
+	for _, saved := range []string{
+		`{"entries": [{"animation": "gradient-test", "seconds": 5}], "playing": true, "position": 3, "order": [0]}`,
+		`{"entries": [{"animation": "gradient-test", "seconds": 5}], "playing": true, "position": 0, "order": [7]}`,
+		`{"entries": [], "playing": true}`,
+		`{"entries": [{"animation": "gradient-test", "seconds": 0}, {"animation": "nope", "seconds": 5}], "playing": true, "order": [1, 0]}`,
+	} {
+		assert.NoError(t, ioutil.WriteFile(path, []byte(saved), 0644))
+		p, err := NewPlaylist(NewControl(), store, path)
+		assert.NoError(t, err, saved)
+		status := p.Status()
+		assert.Equal(t, 0, status.Position, saved)
+		assert.Equal(t, len(status.Entries), len(status.Order), saved)
+		assert.Equal(t, len(status.Entries) > 0, status.Playing, saved)
+		for _, e := range status.Entries {
+			assert.True(t, e.Seconds > 0, saved)
+		}
+	}
+
+	// A deleted preset's entry is kept, to be skipped when it comes up.
+	kept := `{"entries": [{"preset": "gone", "seconds": 5}, {"animation": "gradient-test", "seconds": 0}], "order": [1, 0]}`
+	assert.NoError(t, ioutil.WriteFile(path, []byte(kept), 0644))
+	p, err := NewPlaylist(NewControl(), store, path)
+	assert.NoError(t, err)
+	assert.Equal(t, []PlaylistEntry{{Preset: "gone", Seconds: 5}}, p.Status().Entries)
+	assert.Equal(t, []int{0}, p.Status().Order)
+}
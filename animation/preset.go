@@ -0,0 +1,242 @@
+package animation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const presetExt = ".json"
+
+var (
+	presetNamePattern = regexp.MustCompile("^[A-Za-z0-9][A-Za-z0-9 _-]{0,63}$")
+
+	ErrPresetNotFound    = errors.New("no such preset")
+	ErrPresetExists      = errors.New("a preset with that name already exists")
+	ErrInvalidPresetName = errors.New("preset names are up to 64 letters, digits, spaces, _ and -")
+)
+
+// Preset is a saved look: the animation and every var, color and modulator it was running with.
+type Preset struct {
+	Name          string               `json:"name"`
+	Animation     string               `json:"animation"`
+	Vars          map[string]float64   `json:"vars"`
+	Colors        map[string]string    `json:"colors"`
+	Modulators    map[string]Modulator `json:"modulators,omitempty"`
+	HueModulators map[string]Modulator `json:"hueModulators,omitempty"`
+}
+
+// PresetStore keeps presets as one JSON file each in a directory, so they can be copied
+// between globes or edited by hand.
+type PresetStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewPresetStore(dir string) (*PresetStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &PresetStore{dir: dir}, nil
+}
+
+// CheckPresetName returns ErrInvalidPresetName unless the name is letters, digits, spaces, _ and -,
+// which keeps it a plain file name.
+func CheckPresetName(name string) error {
+	if !presetNamePattern.MatchString(name) {
+		return ErrInvalidPresetName
+	}
+	return nil
+}
+
+// Snapshot returns the state of the control's animation as a preset. Global params like brightness
+// are left out, so recalling a preset, e.g. from the playlist, doesn't undo the schedule's brightness.
+func (c *Control) Snapshot(name string) Preset {
+	animation := c.namespaces()[:1]
+	vars, colors := c.values(animation)
+	modulators, hueModulators := c.modulators(animation)
+	return Preset{Name: name, Animation: animation[0], Vars: vars, Colors: colors, Modulators: modulators, HueModulators: hueModulators}
+}
+
+// Recall sets every var and color saved in the preset, and swaps the animation's modulators for the preset's.
+// Values and modulators the animation has no param for, or that aren't valid, are skipped, so presets
+// keep working as animations change and after they're edited by hand. So are global params, which
+// presets saved before they were left out of Snapshot may have.
+func (c *Control) Recall(p Preset) {
+	c.recall(p, c.namespaces()[:1])
+}
+
+// recall is Recall for the params of the given namespaces.
+func (c *Control) recall(p Preset, namespaces []string) {
+	// check returns an error unless the value is for a param of the type in one of the namespaces.
+	check := func(name, paramType string, v interface{}) error {
+		if ns, _, ok := c.resolve(name); ok && !contains(namespaces, ns) {
+			return fmt.Errorf("%s isn't one of the animation's params", name)
+		}
+		return c.check(name, paramType, v)
+	}
+	vars := make(map[string]float64, len(p.Vars))
+	for name, v := range p.Vars {
+		if err := check(name, ParamNumber, v); err != nil {
+			log.Printf("Skipping %s from preset %s: %v", name, p.Name, err)
+			continue
+		}
+		vars[name] = v
+	}
+	colors := make(map[string]string, len(p.Colors))
+	for name, v := range p.Colors {
+		if err := check(name, ParamColor, v); err != nil {
+			log.Printf("Skipping %s from preset %s: %v", name, p.Name, err)
+			continue
+		}
+		colors[name] = v
+	}
+	c.Update(vars, colors)
+	modulators := make(map[string]Modulator, len(p.Modulators))
+	for name, m := range p.Modulators {
+		if err := c.checkModulator(namespaces, name, ParamNumber, m); err != nil {
+			log.Printf("Skipping modulator %s from preset %s: %v", name, p.Name, err)
+			continue
+		}
+		modulators[name] = m
+	}
+	hueModulators := make(map[string]Modulator, len(p.HueModulators))
+	for name, m := range p.HueModulators {
+		if err := c.checkModulator(namespaces, name, ParamColor, m); err != nil {
+			log.Printf("Skipping hue modulator %s from preset %s: %v", name, p.Name, err)
+			continue
+		}
+		hueModulators[name] = m
+	}
+	c.replaceModulators(namespaces, modulators, hueModulators)
+}
+
+// Show switches to the preset's animation, if it has one, and recalls it.
+func (c *Control) Show(p Preset) error {
+	if p.Animation != "" {
+		if err := Select(p.Animation); err != nil {
+			return fmt.Errorf("%s: %v", p.Animation, err)
+		}
+	}
+	c.Recall(p)
+	return nil
+}
+
+// List returns every preset, sorted by name. Files that can't be read are logged and left out,
+// so one bad file edited by hand doesn't hide the rest.
+func (s *PresetStore) List() ([]Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	presets := make([]Preset, 0)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), presetExt) {
+			continue
+		}
+		p, err := s.read(strings.TrimSuffix(file.Name(), presetExt))
+		if err != nil {
+			log.Printf("Skipping preset file %s: %v", file.Name(), err)
+			continue
+		}
+		presets = append(presets, p)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets, nil
+}
+
+func (s *PresetStore) Get(name string) (Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(name)
+}
+
+// Save stores the preset under its name, replacing any preset with the same name.
+func (s *PresetStore) Save(p Preset) error {
+	if err := CheckPresetName(p.Name); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(p)
+}
+
+func (s *PresetStore) Rename(from, to string) error {
+	if err := CheckPresetName(to); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.read(from)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	if _, err := os.Stat(s.path(to)); err == nil {
+		return ErrPresetExists
+	}
+	p.Name = to
+	if err := s.write(p); err != nil {
+		return err
+	}
+	return os.Remove(s.path(from))
+}
+
+func (s *PresetStore) Delete(name string) error {
+	if CheckPresetName(name) != nil {
+		return ErrPresetNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Remove(s.path(name))
+	if os.IsNotExist(err) {
+		return ErrPresetNotFound
+	}
+	return err
+}
+
+func (s *PresetStore) path(name string) string {
+	return filepath.Join(s.dir, name+presetExt)
+}
+
+// read must be called with the lock held.
+func (s *PresetStore) read(name string) (Preset, error) {
+	var p Preset
+	if CheckPresetName(name) != nil {
+		return p, ErrPresetNotFound
+	}
+	bytes, err := ioutil.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return p, ErrPresetNotFound
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(bytes, &p); err != nil {
+		return p, fmt.Errorf("error reading preset %s: %v", name, err)
+	}
+	// The file name wins, so a preset copied to a new file is listed under its new name.
+	p.Name = name
+	return p, nil
+}
+
+// write must be called with the lock held.
+func (s *PresetStore) write(p Preset) error {
+	bytes, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(p.Name), bytes)
+}
@@ -0,0 +1,70 @@
+package animation
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresetStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewPresetStore(dir)
+	assert.NoError(t, err)
+
+	c := NewControl().Namespace("opensimplex")
+	assert.NoError(t, c.SetVar("speed", 0.4))
+	assert.NoError(t, c.SetColorHex("A", "ff0000"))
+	assert.NoError(t, store.Save(c.Snapshot("sunset")))
+	assert.NoError(t, store.Save(c.Snapshot("aurora")))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644))
+	presets, err := store.List()
+	assert.NoError(t, err)
+	assert.Len(t, presets, 2, "broken.json is skipped")
+	assert.Equal(t, "aurora", presets[0].Name)
+	assert.Equal(t, "opensimplex", presets[1].Animation)
+
+	assert.Equal(t, ErrPresetExists, store.Rename("sunset", "aurora"))
+	assert.NoError(t, store.Rename("sunset", "dusk"))
+	_, err = store.Get("sunset")
+	assert.Equal(t, ErrPresetNotFound, err)
+
+	assert.NoError(t, c.SetVar("speed", 0.9))
+	dusk, err := store.Get("dusk")
+	assert.NoError(t, err)
+	c.Recall(dusk)
+	assert.Equal(t, 0.4, c.GetVar("speed"))
+
+	assert.NoError(t, store.Delete("dusk"))
+	assert.Equal(t, ErrPresetNotFound, store.Delete("dusk"))
+}
+
+func TestPresetNames(t *testing.T) {
+	assert.NoError(t, CheckPresetName("Late night 2"))
+	assert.Equal(t, ErrInvalidPresetName, CheckPresetName("../state"))
+	assert.Equal(t, ErrInvalidPresetName, CheckPresetName(""))
+}
+
+func TestPresetsLeaveGlobalsAlone(t *testing.T) {
+	c := NewControl().Namespace("opensimplex")
+	assert.NoError(t, c.SetVar("brightness", 0.8))
+	assert.NoError(t, c.SetModulator("brightness", Modulator{Shape: ShapeSine, Rate: 1}))
+	p := c.Snapshot("calm")
+	assert.NotContains(t, p.Vars, "brightness")
+	assert.NotContains(t, p.Modulators, "brightness")
+	assert.Contains(t, p.Vars, "speed")
+
+	// As a schedule rule would, then an older preset that still has the brightness.
+	assert.NoError(t, c.SetVar("brightness", 0.2))
+	p.Vars["brightness"] = 1
+	c.Recall(p)
+	vars, _ := c.Values()
+	assert.Equal(t, 0.2, vars["brightness"])
+	modulators, _ := c.AllModulators()
+	assert.Contains(t, modulators, "brightness", "global modulators are kept too")
+}
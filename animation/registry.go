@@ -0,0 +1,148 @@
+package animation
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+var ErrUnknownAnimation = errors.New("no animation with that name")
+
+// Info describes an animation and the params it reads.
+type Info struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Params      []Param `json:"params"`
+}
+
+type registration struct {
+	new         func(control Control) Animation
+	description string
+	params      []Param
+}
+
+// animations holds every animation that can be selected by name.
+var animations = map[string]registration{
+	"opensimplex": {
+		new:         func(control Control) Animation { return NewOpenSimplexAnimation(control) },
+		description: "Flowing 4D simplex noise mapped through a four color gradient",
+		params:      openSimplexParams,
+	},
+	"geo": {
+		new:         NewGeoAnimation,
+		description: "Randomly colored bubbles that grow and fade",
+	},
+	"geo2": {
+		new:         NewGeoAnimation2,
+		description: "Comets travelling along great circles",
+	},
+	"geojson": {
+		new:         func(control Control) Animation { return NewGeojsonAnimation(control) },
+		description: "Lights up a country from animation/custom.geo.json",
+	},
+	"gradient-test": {
+		new:         func(control Control) Animation { return NewGradientTestAnimation(control) },
+		description: "The gradient from south to north, for checking the colors",
+		params:      gradientParams,
+	},
+	"brightness-test": {
+		new:         func(control Control) Animation { return NewBrightnessTestAnimation(control) },
+		description: "A red ramp around the globe, for checking the low end of the brightness curve",
+	},
+}
+
+var (
+	selectMu sync.Mutex
+	// current is the name of the selected animation. Start switches to it before the next frame,
+	// using the transition.
+	current           = "opensimplex"
+	switched          bool
+	pendingTransition Transition
+)
+
+// Names returns the name of every animation, sorted.
+func Names() []string {
+	names := make([]string, 0, len(animations))
+	for name := range animations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Describe returns the description and params of the named animation.
+func Describe(name string) (Info, error) {
+	r, ok := animations[name]
+	if !ok {
+		return Info{}, ErrUnknownAnimation
+	}
+	params := r.params
+	if params == nil {
+		params = []Param{}
+	}
+	return Info{Name: name, Description: r.description, Params: params}, nil
+}
+
+// Current returns the name of the selected animation.
+func Current() string {
+	selectMu.Lock()
+	defer selectMu.Unlock()
+	return current
+}
+
+// Select switches to the named animation with the DefaultTransition. The running frame finishes first.
+func Select(name string) error {
+	return SelectWith(name, DefaultTransition)
+}
+
+// SelectWith switches to the named animation with the given transition.
+func SelectWith(name string, t Transition) error {
+	if _, ok := animations[name]; !ok {
+		return ErrUnknownAnimation
+	}
+	if err := t.Check(); err != nil {
+		return err
+	}
+	selectMu.Lock()
+	defer selectMu.Unlock()
+	if name != current {
+		current = name
+		switched = true
+		pendingTransition = t
+		selectionChanged()
+	}
+	return nil
+}
+
+// nextAnimation returns the newly selected animation, its name and the transition to it, or nil if
+// the selection hasn't changed. If the new animation can't be built the running one is selected again.
+func nextAnimation(control Control, running string) (Animation, string, Transition) {
+	selectMu.Lock()
+	defer selectMu.Unlock()
+	if !switched {
+		return nil, running, pendingTransition
+	}
+	switched = false
+	a, err := newAnimation(control, current)
+	if err != nil {
+		log.Printf("Error starting animation %s, staying with %s: %v", current, running, err)
+		current = running
+		selectionChanged()
+		return nil, running, pendingTransition
+	}
+	log.Printf("Switched to animation %s", current)
+	return a, current, pendingTransition
+}
+
+// newAnimation builds the named animation, with a control for its namespace. Some constructors panic,
+// e.g. when a data file is missing, which shouldn't take the globe down with it.
+func newAnimation(control Control, name string) (a Animation, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return animations[name].new(control.Namespace(name)), nil
+}
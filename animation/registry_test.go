@@ -0,0 +1,37 @@
+package animation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectSwitchesBetweenFrames(t *testing.T) {
+	defer Select(Current())
+	control := NewControl()
+
+	assert.Equal(t, ErrUnknownAnimation, Select("nope"))
+	assert.NoError(t, Select("gradient-test"))
+	assert.Equal(t, "gradient-test", Current())
+
+	a, name, transition := nextAnimation(control, "opensimplex")
+	assert.IsType(t, &GradientTestAnimation{}, a)
+	assert.Equal(t, "gradient-test", name)
+	assert.Equal(t, DefaultTransition, transition)
+
+	a, name, _ = nextAnimation(control, name)
+	assert.Nil(t, a, "nothing changed since the last switch")
+	assert.Equal(t, "gradient-test", name)
+}
+
+func TestSelectRevertsWhenAnimationFails(t *testing.T) {
+	animations["broken"] = registration{new: func(control Control) Animation { panic("missing data file") }}
+	defer delete(animations, "broken")
+	defer Select(Current())
+
+	assert.NoError(t, Select("broken"))
+	a, name, _ := nextAnimation(NewControl(), "gradient-test")
+	assert.Nil(t, a)
+	assert.Equal(t, "gradient-test", name)
+	assert.Equal(t, "gradient-test", Current())
+}
@@ -0,0 +1,276 @@
+package animation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// ActionBrightness sets the brightness var.
+	ActionBrightness = "brightness"
+	// ActionPreset switches to a preset, as recalling it over the API does.
+	ActionPreset = "preset"
+	// ActionBlank turns the globe off, leaving the animation running, until an ActionUnblank rule runs.
+	ActionBlank   = "blank"
+	ActionUnblank = "unblank"
+
+	Sunrise = "sunrise"
+	Sunset  = "sunset"
+
+	scheduleTick = time.Second
+	// nextSearch is how far ahead Status looks for the next time each rule runs.
+	nextSearch = 8 * 24 * time.Hour
+)
+
+// Rule does something at the times of a cron expression, or at sunrise or sunset.
+type Rule struct {
+	// Cron is minute, hour, day of month, month and day of week in local time, e.g. "0 22 * * 1-5"
+	// for 10pm on weekdays.
+	Cron string `json:"cron,omitempty"`
+	// Sun is "sunrise" or "sunset" at the schedule's location, moved by OffsetMinutes,
+	// e.g. -30 for half an hour before.
+	Sun           string  `json:"sun,omitempty"`
+	OffsetMinutes float64 `json:"offsetMinutes,omitempty"`
+
+	Action     string   `json:"action"`
+	Brightness *float64 `json:"brightness,omitempty"`
+	Preset     string   `json:"preset,omitempty"`
+}
+
+// Schedule is the rules and where the globe is, for working out sunrise and sunset.
+// It's saved to disk along with whether the globe is blanked.
+type Schedule struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Rules     []Rule  `json:"rules"`
+	Blank     bool    `json:"blank"`
+}
+
+// ScheduleStatus is the schedule plus today's sunrise and sunset and when each rule runs next.
+type ScheduleStatus struct {
+	Schedule
+	Sunrise *time.Time   `json:"sunrise,omitempty"`
+	Sunset  *time.Time   `json:"sunset,omitempty"`
+	Next    []*time.Time `json:"next"`
+}
+
+// Scheduler runs the schedule's rules as their times come round.
+type Scheduler struct {
+	control Control
+	presets *PresetStore
+	path    string
+
+	mu       sync.Mutex
+	schedule Schedule
+	crons    []cron
+	// last is when the rules were last checked; rules due since then run on the next tick.
+	last time.Time
+}
+
+// NewScheduler returns the schedule saved at path, or an empty one if nothing has been saved.
+func NewScheduler(control Control, presets *PresetStore, path string) (*Scheduler, error) {
+	s := &Scheduler{control: control, presets: presets, path: path, last: time.Now()}
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	var schedule Schedule
+	if err := json.Unmarshal(bytes, &schedule); err != nil {
+		return s, fmt.Errorf("error reading schedule %s: %v", path, err)
+	}
+	// The blanking is restored even if the rules can't be, so the globe doesn't light up at night.
+	s.schedule.Blank = schedule.Blank
+	Blank(schedule.Blank)
+	// Presets may have been deleted since the schedule was saved. Rules for them log an error when
+	// they run rather than losing the whole schedule.
+	crons, err := s.check(schedule, false)
+	if err != nil {
+		return s, fmt.Errorf("error in schedule %s: %v", path, err)
+	}
+	s.schedule, s.crons = schedule, crons
+	return s, nil
+}
+
+// Set replaces the location and rules. Nothing changes if any of them is invalid.
+func (s *Scheduler) Set(lat, lon float64, rules []Rule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	schedule := Schedule{Latitude: lat, Longitude: lon, Rules: rules, Blank: s.schedule.Blank}
+	crons, err := s.check(schedule, true)
+	if err != nil {
+		return err
+	}
+	s.schedule, s.crons = schedule, crons
+	return s.save()
+}
+
+// Check returns an error if the location or any of the rules is invalid.
+func (s *Scheduler) Check(schedule Schedule) error {
+	_, err := s.check(schedule, true)
+	return err
+}
+
+// check validates the schedule and returns the parsed cron expressions, one per rule.
+// If presets is true, preset rules must name a preset that exists.
+func (s *Scheduler) check(schedule Schedule, presets bool) ([]cron, error) {
+	if math.IsNaN(schedule.Latitude) || math.Abs(schedule.Latitude) > 90 {
+		return nil, errors.New("latitude must be between -90 and 90")
+	}
+	if math.IsNaN(schedule.Longitude) || math.Abs(schedule.Longitude) > 180 {
+		return nil, errors.New("longitude must be between -180 and 180")
+	}
+	crons := make([]cron, len(schedule.Rules))
+	for i, r := range schedule.Rules {
+		var err error
+		switch {
+		case r.Cron != "" && r.Sun != "":
+			err = errors.New("a rule runs on a cron or the sun, not both")
+		case r.Cron != "":
+			crons[i], err = parseCron(r.Cron)
+		case r.Sun != Sunrise && r.Sun != Sunset:
+			err = errors.New(`a rule needs a cron or a sun of "sunrise" or "sunset"`)
+		}
+		if err == nil {
+			err = s.checkAction(r, presets)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+	}
+	return crons, nil
+}
+
+func (s *Scheduler) checkAction(r Rule, presets bool) error {
+	switch r.Action {
+	case ActionBrightness:
+		if r.Brightness == nil {
+			return errors.New("a brightness rule needs a brightness")
+		}
+		p, _ := declared(GlobalNamespace, "brightness")
+		_, err := p.check(*r.Brightness)
+		return err
+	case ActionPreset:
+		if !presets {
+			return CheckPresetName(r.Preset)
+		}
+		_, err := s.presets.Get(r.Preset)
+		if err != nil {
+			return fmt.Errorf("%s: %v", r.Preset, err)
+		}
+		return nil
+	case ActionBlank, ActionUnblank:
+		return nil
+	}
+	return fmt.Errorf("unknown action %q, use brightness, preset, blank or unblank", r.Action)
+}
+
+// Status returns the schedule with today's sunrise and sunset and when each rule runs next.
+func (s *Scheduler) Status() ScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	status := ScheduleStatus{Schedule: s.schedule, Next: make([]*time.Time, len(s.schedule.Rules))}
+	if sunrise, sunset, ok := SunTimes(now, s.schedule.Latitude, s.schedule.Longitude); ok {
+		status.Sunrise, status.Sunset = &sunrise, &sunset
+	}
+	for i := range s.schedule.Rules {
+		if next, ok := s.next(i, now, now.Add(nextSearch)); ok {
+			status.Next[i] = &next
+		}
+	}
+	return status
+}
+
+// Run checks the rules every second. It never returns.
+func (s *Scheduler) Run() {
+	for now := range time.Tick(scheduleTick) {
+		s.tick(now)
+	}
+}
+
+// tick runs, in order, every rule that came due since the last tick.
+func (s *Scheduler) tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.schedule.Rules {
+		if _, ok := s.next(i, s.last, now); ok {
+			s.run(r)
+		}
+	}
+	s.last = now
+}
+
+// next returns the first time after from, and no later than to, that rule i runs.
+func (s *Scheduler) next(i int, from, to time.Time) (time.Time, bool) {
+	r := s.schedule.Rules[i]
+	if r.Cron != "" {
+		for t := from.Truncate(time.Minute).Add(time.Minute); !t.After(to); t = t.Add(time.Minute) {
+			if s.crons[i].matches(t.Local()) {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+	offset := time.Duration(r.OffsetMinutes * float64(time.Minute))
+	// Start the day before, as an offset can move a sunset past midnight.
+	for day := from.Local().AddDate(0, 0, -1); !day.After(to.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		sunrise, sunset, ok := SunTimes(day, s.schedule.Latitude, s.schedule.Longitude)
+		if !ok {
+			continue
+		}
+		t := sunrise
+		if r.Sun == Sunset {
+			t = sunset
+		}
+		t = t.Add(offset)
+		if t.After(from) && !t.After(to) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// run does the rule's action. It must be called with the lock held.
+func (s *Scheduler) run(r Rule) {
+	switch r.Action {
+	case ActionBrightness:
+		log.Printf("Schedule: brightness %.2f", *r.Brightness)
+		if err := s.control.SetVar("brightness", *r.Brightness); err != nil {
+			log.Printf("Error setting brightness from the schedule: %v", err)
+		}
+	case ActionPreset:
+		log.Printf("Schedule: preset %s", r.Preset)
+		preset, err := s.presets.Get(r.Preset)
+		if err == nil {
+			err = s.control.Show(preset)
+		}
+		if err != nil {
+			log.Printf("Error showing preset %s from the schedule: %v", r.Preset, err)
+		}
+	case ActionBlank, ActionUnblank:
+		log.Printf("Schedule: %s", r.Action)
+		s.schedule.Blank = r.Action == ActionBlank
+		Blank(s.schedule.Blank)
+		if err := s.save(); err != nil {
+			log.Printf("Error saving schedule to %s: %v", s.path, err)
+		}
+	}
+}
+
+func (s *Scheduler) save() error {
+	bytes, err := json.MarshalIndent(s.schedule, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, bytes)
+}
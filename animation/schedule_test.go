@@ -0,0 +1,92 @@
+package animation
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerRunsRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewPresetStore(dir)
+	assert.NoError(t, err)
+	c := NewControl()
+	c.SetVar("brightness", 1.0)
+	path := filepath.Join(dir, "schedule.json")
+	s, err := NewScheduler(c, store, path)
+	assert.NoError(t, err)
+	defer Blank(false)
+
+	dim := 0.25
+	rules := []Rule{
+		{Cron: "30 21 * * *", Action: ActionBrightness, Brightness: &dim},
+		{Cron: "0 23 * * *", Action: ActionBlank},
+		{Sun: Sunrise, OffsetMinutes: 30, Action: ActionUnblank},
+	}
+	assert.Error(t, s.Set(0, 0, []Rule{{Cron: "0 23 * * *", Action: ActionPreset, Preset: "missing"}}))
+	assert.Error(t, s.Set(91, 0, rules))
+	tooBright := 1.5
+	assert.Error(t, s.Set(0, 0, []Rule{{Cron: "0 23 * * *", Action: ActionBrightness, Brightness: &tooBright}}))
+
+	// Put the globe in the local time zone, so sunrise is early in the local morning.
+	day := time.Date(2024, 6, 21, 0, 0, 0, 0, time.Local)
+	_, offset := day.Zone()
+	lon := math.Max(-180, math.Min(180, float64(offset)/3600*15))
+	assert.NoError(t, s.Set(51.5, lon, rules))
+	s.last = day.Add(21 * time.Hour)
+	s.tick(day.Add(21*time.Hour + 29*time.Minute))
+	assert.Equal(t, 1.0, c.GetVar("brightness"))
+	s.tick(day.Add(21*time.Hour + 30*time.Minute))
+	assert.Equal(t, 0.25, c.GetVar("brightness"))
+	s.tick(day.Add(23*time.Hour + 5*time.Minute))
+	assert.True(t, Blanked())
+
+	// The globe stays blank across a restart.
+	Blank(false)
+	restored, err := NewScheduler(c, store, path)
+	assert.NoError(t, err)
+	assert.True(t, Blanked())
+	assert.Len(t, restored.Status().Rules, 3)
+
+	sunrise, _, ok := SunTimes(day.AddDate(0, 0, 1), 51.5, lon)
+	assert.True(t, ok)
+	next, ok := s.next(2, day.Add(23*time.Hour), day.AddDate(0, 0, 2))
+	assert.True(t, ok)
+	assert.Equal(t, sunrise.Add(30*time.Minute), next)
+	s.tick(next)
+	assert.False(t, Blanked())
+}
+
+func TestSchedulerKeepsRulesForDeletedPresets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	store, err := NewPresetStore(dir)
+	assert.NoError(t, err)
+	c := NewControl()
+	assert.NoError(t, store.Save(c.Snapshot("evening")))
+	path := filepath.Join(dir, "schedule.json")
+	s, err := NewScheduler(c, store, path)
+	assert.NoError(t, err)
+	defer Blank(false)
+
+	rules := []Rule{{Sun: Sunset, Action: ActionPreset, Preset: "evening"}, {Cron: "0 23 * * *", Action: ActionBlank}}
+	assert.NoError(t, s.Set(51.5, -0.1, rules))
+	s.mu.Lock()
+	s.run(Rule{Action: ActionBlank})
+	s.mu.Unlock()
+	assert.NoError(t, store.Delete("evening"))
+
+	Blank(false)
+	restored, err := NewScheduler(c, store, path)
+	assert.NoError(t, err)
+	assert.True(t, Blanked())
+	assert.Len(t, restored.Status().Rules, 2)
+}
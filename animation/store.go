@@ -0,0 +1,400 @@
+package animation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+)
+
+// GlobalNamespace holds the GlobalParams. Every animation's params are in a namespace named after it.
+const GlobalNamespace = "global"
+
+var ErrUnknownParam = errors.New("no param with that name")
+
+// GradientStop is one color of a ParamGradient, at a position from 0 to 1.
+type GradientStop struct {
+	Color    string  `json:"color"`
+	Position float64 `json:"position"`
+}
+
+// Point is a ParamPoint, in degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Store holds the value of every param, by namespace. A param that hasn't been set reads as its default,
+// and only values that have been set are saved, so a new default takes effect everywhere it wasn't changed.
+type Store struct {
+	// version and saved are first so they're 64 bit aligned for sync/atomic on 32 bit ARM.
+	version uint64
+	// saved is the version last loaded from or saved to disk. See Persist.
+	saved uint64
+
+	mu     sync.Mutex
+	values map[string]map[string]interface{}
+	// raw keeps loaded values that no param accepts, e.g. those of an animation that's since been removed,
+	// so they're saved again exactly as they were loaded.
+	raw map[string]map[string]json.RawMessage
+
+	modulators    map[string]map[string]Modulator
+	hueModulators map[string]map[string]Modulator
+
+	subscriptions map[*subscription]struct{}
+}
+
+// storeJSON is how a Store is saved.
+type storeJSON struct {
+	Params        map[string]map[string]json.RawMessage `json:"params"`
+	Modulators    map[string]map[string]Modulator       `json:"modulators,omitempty"`
+	HueModulators map[string]map[string]Modulator       `json:"hueModulators,omitempty"`
+}
+
+func NewStore() *Store {
+	return &Store{
+		values:        make(map[string]map[string]interface{}),
+		raw:           make(map[string]map[string]json.RawMessage),
+		modulators:    make(map[string]map[string]Modulator),
+		hueModulators: make(map[string]map[string]Modulator),
+		subscriptions: make(map[*subscription]struct{}),
+	}
+}
+
+// Namespaces returns GlobalNamespace followed by every animation's namespace.
+func Namespaces() []string {
+	return append([]string{GlobalNamespace}, Names()...)
+}
+
+// declared returns the param with the name in the namespace.
+func declared(namespace, name string) (Param, bool) {
+	params := GlobalParams
+	if namespace != GlobalNamespace {
+		params = animations[namespace].params
+	}
+	for _, p := range params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// Get returns the param's value, or its default if it hasn't been set.
+func (s *Store) Get(namespace, name string) (interface{}, error) {
+	p, ok := declared(namespace, name)
+	if !ok {
+		return nil, fmt.Errorf("%s.%s: %v", namespace, name, ErrUnknownParam)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.values[namespace][name]; ok {
+		return v, nil
+	}
+	return p.Default, nil
+}
+
+// Set validates the value and stores it. Numbers may be ints or float64s, and colors may have a leading #.
+func (s *Store) Set(namespace, name string, v interface{}) error {
+	_, err := s.update([]paramValue{{namespace, name, v}})
+	return err
+}
+
+// SetJSON is Set for a value still in JSON.
+func (s *Store) SetJSON(namespace, name string, raw json.RawMessage) error {
+	p, ok := declared(namespace, name)
+	if !ok {
+		return fmt.Errorf("%s.%s: %v", namespace, name, ErrUnknownParam)
+	}
+	v, err := p.decode(raw)
+	if err != nil {
+		return err
+	}
+	return s.Set(namespace, name, v)
+}
+
+type paramValue struct {
+	namespace, name string
+	value           interface{}
+}
+
+// update checks every value and then sets them together, so nobody sees half of the change.
+// It returns the params whose values changed.
+func (s *Store) update(values []paramValue) ([]Param, error) {
+	params := make([]Param, len(values))
+	checked := make([]interface{}, len(values))
+	for i, pv := range values {
+		p, ok := declared(pv.namespace, pv.name)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s: %v", pv.namespace, pv.name, ErrUnknownParam)
+		}
+		v, err := p.check(pv.value)
+		if err != nil {
+			return nil, err
+		}
+		params[i], checked[i] = p, v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var changed []Param
+	for i, pv := range values {
+		old, ok := s.values[pv.namespace][pv.name]
+		if s.values[pv.namespace] == nil {
+			s.values[pv.namespace] = make(map[string]interface{})
+		}
+		s.values[pv.namespace][pv.name] = checked[i]
+		delete(s.raw[pv.namespace], pv.name)
+		if !ok || !equalValues(old, checked[i]) {
+			changed = append(changed, params[i])
+			s.changed(pv.namespace, pv.name)
+		}
+	}
+	return changed, nil
+}
+
+// IsSet reports whether the param has been given a value, rather than reading as its default.
+func (s *Store) IsSet(namespace, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.values[namespace][name]
+	return ok
+}
+
+// Values returns the value of every param in the namespace, including those still at their default.
+func (s *Store) Values(namespace string) map[string]interface{} {
+	params := GlobalParams
+	if namespace != GlobalNamespace {
+		params = animations[namespace].params
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make(map[string]interface{}, len(params))
+	for _, p := range params {
+		if v, ok := s.values[namespace][p.Name]; ok {
+			values[p.Name] = v
+		} else {
+			values[p.Name] = p.Default
+		}
+	}
+	return values
+}
+
+// MarshalJSON writes the values that have been set and the modulators.
+func (s *Store) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := storeJSON{
+		Params:        make(map[string]map[string]json.RawMessage),
+		Modulators:    s.modulators,
+		HueModulators: s.hueModulators,
+	}
+	for namespace, raw := range s.raw {
+		for name, v := range raw {
+			if saved.Params[namespace] == nil {
+				saved.Params[namespace] = make(map[string]json.RawMessage)
+			}
+			saved.Params[namespace][name] = v
+		}
+	}
+	for namespace, values := range s.values {
+		for name, v := range values {
+			bytes, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			if saved.Params[namespace] == nil {
+				saved.Params[namespace] = make(map[string]json.RawMessage)
+			}
+			saved.Params[namespace][name] = bytes
+		}
+	}
+	return json.Marshal(saved)
+}
+
+// UnmarshalJSON replaces the store's contents with those written by MarshalJSON. Values that aren't valid
+// for their param are kept, to be saved again, but read as the default.
+func (s *Store) UnmarshalJSON(bytes []byte) error {
+	var saved storeJSON
+	if err := json.Unmarshal(bytes, &saved); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]map[string]interface{})
+	s.raw = make(map[string]map[string]json.RawMessage)
+	for namespace, params := range saved.Params {
+		for name, raw := range params {
+			var err error
+			var v interface{}
+			p, ok := declared(namespace, name)
+			if ok {
+				v, err = p.decode(raw)
+			}
+			if !ok || err != nil {
+				if err != nil {
+					log.Printf("Ignoring saved value of %s.%s: %v", namespace, name, err)
+				}
+				if s.raw[namespace] == nil {
+					s.raw[namespace] = make(map[string]json.RawMessage)
+				}
+				s.raw[namespace][name] = raw
+				continue
+			}
+			if s.values[namespace] == nil {
+				s.values[namespace] = make(map[string]interface{})
+			}
+			s.values[namespace][name] = v
+		}
+	}
+	s.modulators = checkedModulators(saved.Modulators, ParamNumber)
+	s.hueModulators = checkedModulators(saved.HueModulators, ParamColor)
+	s.changed("", "")
+	return nil
+}
+
+// checkedModulators returns the loaded modulators without those that are invalid or aren't for a
+// param of the type, which are logged.
+func checkedModulators(loaded map[string]map[string]Modulator, paramType string) map[string]map[string]Modulator {
+	modulators := make(map[string]map[string]Modulator)
+	for namespace, byName := range loaded {
+		for name, m := range byName {
+			err := m.Check()
+			if p, ok := declared(namespace, name); !ok || p.Type != paramType {
+				err = ErrUnknownParam
+			}
+			if err != nil {
+				log.Printf("Ignoring saved modulator of %s.%s: %v", namespace, name, err)
+				continue
+			}
+			if modulators[namespace] == nil {
+				modulators[namespace] = make(map[string]Modulator)
+			}
+			modulators[namespace][name] = m
+		}
+	}
+	return modulators
+}
+
+// check returns the value as the type the param stores, or an error if it isn't valid for the param.
+func (p Param) check(v interface{}) (interface{}, error) {
+	switch p.Type {
+	case ParamNumber:
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a number, got %v", p.Name, v)
+		}
+		if math.IsNaN(f) || f < p.Min || f > p.Max {
+			return nil, fmt.Errorf("%s must be between %v and %v, got %v", p.Name, p.Min, p.Max, f)
+		}
+		return f, nil
+	case ParamInt:
+		f, ok := toFloat(v)
+		if !ok || f != math.Trunc(f) {
+			return nil, fmt.Errorf("%s must be a whole number, got %v", p.Name, v)
+		}
+		if f < p.Min || f > p.Max {
+			return nil, fmt.Errorf("%s must be between %v and %v, got %v", p.Name, p.Min, p.Max, f)
+		}
+		return int(f), nil
+	case ParamBool:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s must be true or false, got %v", p.Name, v)
+		}
+		return b, nil
+	case ParamEnum:
+		s, _ := v.(string)
+		for _, option := range p.Options {
+			if s == option {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%s must be one of %v, got %v", p.Name, p.Options, v)
+	case ParamColor:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a 6 digit hex color like ff00ff, got %v", p.Name, v)
+		}
+		return CheckColorHex(p.Name, s)
+	case ParamGradient:
+		stops, ok := v.([]GradientStop)
+		if !ok || len(stops) == 0 {
+			return nil, fmt.Errorf("%s must be a list of colors and positions", p.Name)
+		}
+		checked := make([]GradientStop, len(stops))
+		for i, stop := range stops {
+			hex, err := CheckColorHex(p.Name, stop.Color)
+			if err != nil {
+				return nil, err
+			}
+			if math.IsNaN(stop.Position) || stop.Position < 0 || stop.Position > 1 {
+				return nil, fmt.Errorf("%s positions must be between 0 and 1, got %v", p.Name, stop.Position)
+			}
+			checked[i] = GradientStop{Color: hex, Position: stop.Position}
+		}
+		sort.SliceStable(checked, func(i, j int) bool { return checked[i].Position < checked[j].Position })
+		return checked, nil
+	case ParamPoint:
+		pt, ok := v.(Point)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a point with a lat and lon", p.Name)
+		}
+		if math.IsNaN(pt.Lat) || math.Abs(pt.Lat) > 90 || math.IsNaN(pt.Lon) || math.Abs(pt.Lon) > 180 {
+			return nil, fmt.Errorf("%s must have a lat between -90 and 90 and a lon between -180 and 180", p.Name)
+		}
+		return pt, nil
+	}
+	return nil, fmt.Errorf("%s has unknown type %s", p.Name, p.Type)
+}
+
+// decode reads a JSON value for the param and checks it.
+func (p Param) decode(raw json.RawMessage) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch p.Type {
+	case ParamGradient:
+		var stops []GradientStop
+		err = json.Unmarshal(raw, &stops)
+		v = stops
+	case ParamPoint:
+		var pt Point
+		err = json.Unmarshal(raw, &pt)
+		v = pt
+	default:
+		err = json.Unmarshal(raw, &v)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", p.Name, err)
+	}
+	return p.check(v)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func equalValues(a, b interface{}) bool {
+	as, ok := a.([]GradientStop)
+	if !ok {
+		return a == b
+	}
+	bs := b.([]GradientStop)
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package animation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedParams(t *testing.T) {
+	saved := GlobalParams
+	defer func() { GlobalParams = saved }()
+	GlobalParams = append(append([]Param{}, GlobalParams...),
+		IntParam("count", "Count", 1, 10, 3, ""),
+		BoolParam("mirror", "Mirror", false),
+		EnumParam("mode", "Mode", []string{"spiral", "bands"}, "bands"),
+		GradientParam("palette", "Palette", []GradientStop{{"000000", 0}, {"ffffff", 1}}),
+		PointParam("home", "Home", Point{Lat: 51.5, Lon: -0.1}),
+	)
+
+	s := NewStore()
+	v, err := s.Get(GlobalNamespace, "count")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	assert.NoError(t, s.Set(GlobalNamespace, "count", 7.0))
+	assert.Error(t, s.Set(GlobalNamespace, "count", 7.5))
+	assert.Error(t, s.Set(GlobalNamespace, "count", 11))
+	assert.NoError(t, s.Set(GlobalNamespace, "mirror", true))
+	assert.Error(t, s.Set(GlobalNamespace, "mirror", 1.0))
+	assert.NoError(t, s.Set(GlobalNamespace, "mode", "spiral"))
+	assert.Error(t, s.Set(GlobalNamespace, "mode", "zigzag"))
+	assert.NoError(t, s.Set(GlobalNamespace, "palette", []GradientStop{{"#FF0000", 1}, {"0000ff", 0.25}}))
+	assert.Error(t, s.Set(GlobalNamespace, "palette", []GradientStop{{"ff0000", 1.5}}))
+	assert.NoError(t, s.SetJSON(GlobalNamespace, "home", json.RawMessage(`{"lat": -33.87, "lon": 151.21}`)))
+	assert.Error(t, s.SetJSON(GlobalNamespace, "home", json.RawMessage(`{"lat": 95, "lon": 0}`)))
+	assert.Error(t, s.Set(GlobalNamespace, "nothing", 1.0))
+
+	c := Control{store: s}
+	assert.Equal(t, 7, c.GetInt("count"))
+	assert.Equal(t, 7.0, c.GetVar("count"))
+	assert.True(t, c.GetBool("mirror"))
+	assert.Equal(t, "spiral", c.GetEnum("mode"))
+	assert.Equal(t, []GradientStop{{"0000ff", 0.25}, {"ff0000", 1}}, c.GetGradient("palette"), "stops are sorted")
+	assert.Equal(t, Point{Lat: -33.87, Lon: 151.21}, c.GetPoint("home"))
+
+	bytes, err := json.Marshal(s)
+	assert.NoError(t, err)
+	restored := NewStore()
+	assert.NoError(t, json.Unmarshal(bytes, restored))
+	assert.Equal(t, s.Values(GlobalNamespace), restored.Values(GlobalNamespace))
+	again, _ := json.Marshal(restored)
+	assert.JSONEq(t, string(bytes), string(again))
+}
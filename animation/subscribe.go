@@ -0,0 +1,122 @@
+package animation
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// selections counts the times another animation has been selected. It's part of every
+	// Control's Version, since selecting an animation changes which params a control sees.
+	selections uint64
+
+	selectionMu            sync.Mutex
+	selectionSubscriptions = make(map[*subscription]struct{})
+)
+
+// subscription gets a value on ch when a param it's watching changes. An empty namespace watches
+// every namespace, and an empty name every param in the namespace.
+type subscription struct {
+	namespace, name string
+	ch              chan struct{}
+}
+
+func (s *subscription) watches(namespace, name string) bool {
+	return (s.namespace == "" || namespace == "" || s.namespace == namespace) &&
+		(s.name == "" || name == "" || s.name == name)
+}
+
+// changed counts a change to the param, or to every param in the namespace if name is empty, or to
+// everything if namespace is empty too, and lets its subscribers know. It must be called with the lock held.
+func (s *Store) changed(namespace, name string) {
+	atomic.AddUint64(&s.version, 1)
+	for sub := range s.subscriptions {
+		if sub.watches(namespace, name) {
+			sub.notify()
+		}
+	}
+}
+
+// notify sends without blocking. The channel holds one value, so a subscriber that's busy sees
+// a burst of changes once.
+func (s *subscription) notify() {
+	select {
+	case s.ch <- struct{}{}:
+	default:
+	}
+}
+
+// selectionChanged counts a newly selected animation and lets everyone subscribed to all changes know.
+func selectionChanged() {
+	atomic.AddUint64(&selections, 1)
+	selectionMu.Lock()
+	defer selectionMu.Unlock()
+	for sub := range selectionSubscriptions {
+		sub.notify()
+	}
+}
+
+func (s *Store) subscribe(namespace, name string) *subscription {
+	sub := &subscription{namespace: namespace, name: name, ch: make(chan struct{}, 1)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[sub] = struct{}{}
+	return sub
+}
+
+func (s *Store) unsubscribe(sub *subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, sub)
+}
+
+// Version counts the changes to every param and modulator, and the animations selected. It's cheap
+// enough to check every frame, so animations can keep what they read from the control until it moves on.
+func (c *Control) Version() uint64 {
+	return atomic.LoadUint64(&c.store.version) + atomic.LoadUint64(&selections)
+}
+
+// Subscribe returns a channel that gets a value when the param, or its modulator, changes, and a
+// function to call when the subscriber is done with it. The name is looked up when subscribing,
+// so a control following the selected animation keeps watching the param it found then.
+func (c *Control) Subscribe(name string) (<-chan struct{}, func(), error) {
+	ns, _, ok := c.resolve(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s: %v", name, ErrUnknownParam)
+	}
+	sub := c.store.subscribe(ns, name)
+	return sub.ch, func() { c.store.unsubscribe(sub) }, nil
+}
+
+// SubscribeAll is Subscribe for every param and modulator of every namespace, and for selecting
+// another animation.
+func (c *Control) SubscribeAll() (<-chan struct{}, func()) {
+	// One subscription gets both, so a burst of changes and selections is still seen once.
+	sub := c.store.subscribe("", "")
+	selectionMu.Lock()
+	selectionSubscriptions[sub] = struct{}{}
+	selectionMu.Unlock()
+	return sub.ch, func() {
+		c.store.unsubscribe(sub)
+		selectionMu.Lock()
+		delete(selectionSubscriptions, sub)
+		selectionMu.Unlock()
+	}
+}
+
+// syncedVersion remembers the control's version when an animation last read its params.
+type syncedVersion struct {
+	version uint64
+	synced  bool
+}
+
+// stale reports whether the params might have changed since the last time it returned true.
+func (s *syncedVersion) stale(c *Control) bool {
+	version := c.Version()
+	if s.synced && version == s.version && !c.Modulating() {
+		return false
+	}
+	s.version, s.synced = version, true
+	return true
+}
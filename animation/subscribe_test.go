@@ -0,0 +1,91 @@
+package animation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func received(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	c := NewControl()
+	simplex := c.Namespace("opensimplex")
+	gradient := c.Namespace("gradient-test")
+
+	speed, cancel, err := simplex.Subscribe("speed")
+	assert.NoError(t, err)
+	brightness, _, err := gradient.Subscribe("brightness")
+	assert.NoError(t, err)
+	colorA, _, err := gradient.Subscribe("A")
+	assert.NoError(t, err)
+	all, _ := c.SubscribeAll()
+	_, _, err = gradient.Subscribe("speed")
+	assert.Error(t, err)
+
+	version := c.Version()
+	assert.NoError(t, simplex.SetVar("speed", 0.5))
+	assert.NoError(t, simplex.SetVar("speed", 0.6))
+	assert.Equal(t, version+2, c.Version())
+	assert.True(t, received(speed))
+	assert.False(t, received(speed), "a burst of changes is seen once")
+	assert.True(t, received(all))
+	assert.False(t, received(brightness))
+
+	assert.NoError(t, simplex.SetVar("speed", 0.6))
+	assert.Equal(t, version+2, c.Version(), "setting the same value isn't a change")
+	assert.False(t, received(all))
+
+	assert.NoError(t, simplex.SetColorHex("A", "ff0000"))
+	assert.False(t, received(colorA), "each animation has its own A")
+	assert.NoError(t, simplex.SetVar("brightness", 0.5))
+	assert.True(t, received(brightness), "globals are shared")
+
+	assert.NoError(t, simplex.SetModulator("speed", Modulator{Shape: ShapeSine, Rate: 1}))
+	assert.True(t, received(speed))
+
+	cancel()
+	assert.NoError(t, simplex.RemoveModulator("speed"))
+	assert.False(t, received(speed))
+}
+
+func TestSyncedVersion(t *testing.T) {
+	c := NewControl().Namespace("opensimplex")
+	var synced syncedVersion
+	assert.True(t, synced.stale(&c), "read the first frame")
+	assert.False(t, synced.stale(&c))
+	assert.NoError(t, c.SetVar("speed", 0.5))
+	assert.True(t, synced.stale(&c))
+	assert.False(t, synced.stale(&c))
+	assert.NoError(t, c.SetModulator("speed", Modulator{Shape: ShapeSine, Rate: 1}))
+	assert.True(t, synced.stale(&c))
+	assert.True(t, synced.stale(&c), "modulated values move every frame")
+}
+
+func TestSelectingIsAChange(t *testing.T) {
+	c := NewControl()
+	all, cancel := c.SubscribeAll()
+	defer cancel()
+	simplex := c.Namespace("opensimplex")
+	speed, _, err := simplex.Subscribe("speed")
+	assert.NoError(t, err)
+
+	other := "gradient-test"
+	if Current() == other {
+		other = "opensimplex"
+	}
+	version := c.Version()
+	assert.NoError(t, Select(other))
+	assert.Equal(t, version+1, c.Version())
+	assert.True(t, received(all))
+	assert.False(t, received(speed))
+	assert.NoError(t, Select(other))
+	assert.Equal(t, version+1, c.Version(), "selecting the same animation again isn't a change")
+}
@@ -1,7 +1,6 @@
 package animation
 
 import (
-	"github.com/lucasb-eyer/go-colorful"
 	"time"
 )
 
@@ -12,21 +11,6 @@ type TestPatternAnimation struct {
 }
 
 func NewTestPatternAnimation(control Control) *GradientTestAnimation {
-	colorA := colorful.Hsv(0.0, 1.0, 0.3) // red
-	colorB := colorful.Hsv(0.0, 1.0, 0.0)
-	colorC := colorful.Hsv(234.0, 0.0, 0.0)
-	colorD := colorful.Hsv(234.0, 1.0, 0.3) // purple
-
-	control.SetColor("A", colorA)
-	control.SetColor("B", colorB)
-	control.SetColor("C", colorC)
-	control.SetColor("D", colorD)
-
-	control.SetVar("A", 0.0)
-	control.SetVar("B", 0.1)
-	control.SetVar("C", 0.9)
-	control.SetVar("D", 1.0)
-
 	return &GradientTestAnimation{
 		control: control,
 		lat:     -90.0,
@@ -0,0 +1,133 @@
+package animation
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+const (
+	TransitionCut       = "cut"
+	TransitionCrossfade = "crossfade"
+	// TransitionWipeLatitude brings the new animation in from the southernmost pixels to the north pole.
+	TransitionWipeLatitude = "wipe-latitude"
+	// TransitionWipeLongitude brings the new animation in from west to east, starting at 180 degrees.
+	TransitionWipeLongitude = "wipe-longitude"
+	// TransitionWipeGreatCircle spreads the new animation out from a random pixel, by great circle
+	// distance, until it reaches the other side of the globe.
+	TransitionWipeGreatCircle = "wipe-great-circle"
+
+	// wipeEdge is how much of the wipe is blended rather than cut, as a fraction of the whole distance.
+	wipeEdge = 0.1
+)
+
+var ErrUnknownTransition = errors.New("no transition with that type")
+
+// Transition is how one animation hands over to the next.
+type Transition struct {
+	Type    string  `json:"type"`
+	Seconds float64 `json:"seconds"`
+}
+
+// DefaultTransition is used when an animation is selected without choosing a transition.
+var DefaultTransition = Transition{Type: TransitionCrossfade, Seconds: 2}
+
+func (t Transition) Check() error {
+	switch t.Type {
+	case TransitionCut, TransitionCrossfade, TransitionWipeLatitude, TransitionWipeLongitude, TransitionWipeGreatCircle:
+	default:
+		return ErrUnknownTransition
+	}
+	if math.IsNaN(t.Seconds) || t.Seconds < 0 {
+		return errors.New("transition seconds must not be negative")
+	}
+	return nil
+}
+
+// running is an animation with its own clock, so the incoming animation of a transition starts at zero.
+type running struct {
+	animation  Animation
+	start      time.Time
+	frameCount int
+}
+
+func (r *running) frame(now time.Time) {
+	r.animation.frame(now.Sub(r.start), r.frameCount)
+	r.frameCount++
+}
+
+// transition runs the outgoing and incoming animations side by side and blends their pixels in Lab space.
+type transition struct {
+	Transition
+	from, to *running
+	start    time.Time
+	origin   s2.Point
+}
+
+func newTransition(t Transition, from, to *running, now time.Time) *transition {
+	return &transition{
+		Transition: t,
+		from:       from,
+		to:         to,
+		start:      now,
+		origin:     pixels.getRandomPixel().Point,
+	}
+}
+
+// frame draws both animations and leaves the blend of them in pixels. It returns true once the
+// transition is over, when pixels holds just the incoming animation.
+func (t *transition) frame(now time.Time) bool {
+	progress := 1.0
+	if t.Seconds > 0 {
+		progress = now.Sub(t.start).Seconds() / t.Seconds
+	}
+	if progress >= 1 || t.Type == TransitionCut {
+		t.to.frame(now)
+		return true
+	}
+
+	t.from.frame(now)
+	from := pixels.activeColors()
+	pixels.reset()
+	t.to.frame(now)
+	for i, p := range pixels.active {
+		switch mix := t.mix(p, progress); mix {
+		case 0:
+			p.color = &from[i]
+		case 1:
+		default:
+			c := from[i].BlendLab(*p.color, mix).Clamped()
+			p.color = &c
+		}
+	}
+	return false
+}
+
+// mix returns how much of the incoming animation the pixel shows, from 0 to 1.
+func (t *transition) mix(p *Pixel, progress float64) float64 {
+	var position float64
+	switch t.Type {
+	case TransitionCrossfade:
+		return progress
+	case TransitionWipeLatitude:
+		position = (p.Lat - minVisibleLatitude) / latitudeRange
+	case TransitionWipeLongitude:
+		position = (p.Lon + 180) / 360
+	case TransitionWipeGreatCircle:
+		position = t.origin.Distance(p.Point).Radians() / math.Pi
+	}
+	// The edge starts just before the first pixel and ends just after the last one.
+	mix := (progress*(1+wipeEdge) - position) / wipeEdge
+	return math.Max(0, math.Min(1, mix))
+}
+
+func (p *Pixels) activeColors() []colorful.Color {
+	colors := make([]colorful.Color, len(p.active))
+	for i, pixel := range p.active {
+		colors[i] = *pixel.color
+	}
+	return colors
+}
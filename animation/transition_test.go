@@ -0,0 +1,69 @@
+package animation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+// solidAnimation paints every pixel the same color.
+type solidAnimation struct {
+	color colorful.Color
+}
+
+func (a *solidAnimation) frame(elapsed time.Duration, frameCount int) {
+	for _, p := range pixels.active {
+		c := a.color
+		p.color = &c
+	}
+}
+
+func newTestTransition(transitionType string) (*transition, time.Time) {
+	start := time.Now()
+	from := &running{animation: &solidAnimation{colorful.Color{R: 1}}, start: start}
+	to := &running{animation: &solidAnimation{colorful.Color{B: 1}}, start: start}
+	return newTransition(Transition{Type: transitionType, Seconds: 2}, from, to, start), start
+}
+
+func TestCrossfadeBlendsInLab(t *testing.T) {
+	defer pixels.reset()
+	tr, start := newTestTransition(TransitionCrossfade)
+
+	assert.False(t, tr.frame(start.Add(time.Second)))
+	expected := colorful.Color{R: 1}.BlendLab(colorful.Color{B: 1}, 0.5).Clamped()
+	for _, p := range pixels.active {
+		assert.Equal(t, expected, *p.color)
+	}
+
+	pixels.reset()
+	assert.True(t, tr.frame(start.Add(2*time.Second)))
+	assert.Equal(t, colorful.Color{B: 1}, *pixels.active[0].color)
+}
+
+func TestLatitudeWipeMovesNorth(t *testing.T) {
+	defer pixels.reset()
+	tr, start := newTestTransition(TransitionWipeLatitude)
+	tr.frame(start.Add(time.Second))
+
+	for _, p := range pixels.active {
+		switch {
+		case p.Lat < 0:
+			assert.Equal(t, colorful.Color{B: 1}, *p.color, "south of the equator has the new animation")
+		case p.Lat > 45:
+			assert.Equal(t, colorful.Color{R: 1}, *p.color, "the far north still has the old animation")
+		}
+	}
+}
+
+func TestGreatCircleWipeStartsAtOrigin(t *testing.T) {
+	tr, _ := newTestTransition(TransitionWipeGreatCircle)
+	for _, p := range pixels.active {
+		if p.Point == tr.origin {
+			assert.Equal(t, 0.0, tr.mix(p, 0.0))
+			assert.Equal(t, 1.0, tr.mix(p, 0.1))
+		}
+	}
+	assert.Equal(t, ErrUnknownTransition, Transition{Type: "spin"}.Check())
+}
@@ -0,0 +1,547 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/drichelson/ledicious/animation"
+	"gopkg.in/macaron.v1"
+)
+
+// state is the body of GET and PUT /api/v1/state.
+type state struct {
+	Vars   map[string]float64 `json:"vars"`
+	Colors map[string]string  `json:"colors"`
+}
+
+// value is the body of a single var or color.
+type value struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type animations struct {
+	Current    string   `json:"current"`
+	Animations []string `json:"animations"`
+	// Transition is the one used when none is given.
+	Transition animation.Transition `json:"transition"`
+}
+
+type params struct {
+	Animation string            `json:"animation"`
+	Params    []animation.Param `json:"params"`
+}
+
+// modulators is the body of GET and PUT /api/v1/modulators.
+type modulators struct {
+	Vars map[string]animation.Modulator `json:"vars"`
+	Hues map[string]animation.Modulator `json:"hues"`
+}
+
+// changesTimeout is how long GET /api/v1/changes waits for a change before answering anyway.
+var changesTimeout = 30 * time.Second
+
+// changes is the body of GET /api/v1/changes.
+type changes struct {
+	Version uint64 `json:"version"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// registerAPI adds the JSON API. Every value is a param the running animation or every animation
+// declares, with a type and, for numbers and ints, its own range; GET /api/v1/params lists them.
+// A name is looked up in the running animation's namespace first and then in "global", so
+// "brightness" is the same everywhere unless an animation declares its own. Vars are the number
+// params and colors the color params, as 6 digit hex strings. Names that aren't declared and values
+// outside a param's range are rejected, so a typo doesn't silently do nothing.
+//
+//	GET   /api/v1/state         every var and color
+//	PUT   /api/v1/state         set any of the vars and colors in one go
+//	GET   /api/v1/vars          every var
+//	PATCH /api/v1/vars          set the vars in the body, e.g. {"speed": 0.5}
+//	GET   /api/v1/vars/:name    {"name": "speed", "value": 0.5}
+//	PUT   /api/v1/vars/:name    {"value": 0.5}
+//
+// and the same for colors under /api/v1/colors. Nothing is changed if any value is invalid.
+//
+//	GET    /api/v1/presets               every preset
+//	GET    /api/v1/presets/:name         one preset
+//	PUT    /api/v1/presets/:name         save the current animation and its own vars, colors and modulators, not the global ones
+//	POST   /api/v1/presets/:name/recall  switch to the preset
+//	PATCH  /api/v1/presets/:name         rename the preset, {"name": "new name"}
+//	DELETE /api/v1/presets/:name
+//
+//	GET    /api/v1/animations            {"current": "opensimplex", "animations": ["geo", ...]}
+//	PUT    /api/v1/animations/current    {"name": "geo"}, optionally with "transition": {"type": "crossfade", "seconds": 2}
+//	GET    /api/v1/animations/:name      the animation's description and params
+//	GET    /api/v1/params                the params of the running animation, and those every animation has
+//
+//	GET    /api/v1/playlist              the entries, whether it's playing and what's showing now
+//	PUT    /api/v1/playlist              {"entries": [{"animation": "geo", "seconds": 60}, {"preset": "calm", "seconds": 300}], "shuffle": true}
+//	POST   /api/v1/playlist/play         start, or carry on after a pause
+//	POST   /api/v1/playlist/pause
+//	POST   /api/v1/playlist/skip         move on to the next entry now
+//
+//	GET    /api/v1/modulators            {"vars": {"speed": {"shape": "sine", "rate": 0.1, "depth": 0.2}}, "hues": {"A": {...}}}
+//	PUT    /api/v1/modulators            replace every modulator
+//	PUT    /api/v1/modulators/vars/:name attach a modulator to a var, {"shape": "adsr", "rate": 0.5, "depth": 0.5, "attack": 0.2, ...}
+//	DELETE /api/v1/modulators/vars/:name
+//
+// and the same for colors' hues under /api/v1/modulators/hues. Vars and colors read from the API are
+// the values they're set to; only the animations see them move.
+//
+//	GET    /api/v1/store                 every param of every animation, by namespace: {"global": {"brightness": 1}, "geo": {...}}
+//	GET    /api/v1/store/:namespace      the params of "global" or of one animation
+//	PUT    /api/v1/store/:namespace/:name {"value": ...} of any type, e.g. {"value": {"lat": 51.5, "lon": -0.1}}
+//
+//	GET    /api/v1/changes?since=12      {"version": 13} as soon as any param or modulator has changed, or another animation
+//	                                     has been selected, since version 12, or the same version after 30 seconds.
+//	                                     Without since it answers straight away.
+//
+//	GET    /api/v1/schedule              the location and rules, today's sunrise and sunset and when each rule runs next
+//	PUT    /api/v1/schedule              {"latitude": 51.5, "longitude": -0.1, "rules": [{"sun": "sunset", "offsetMinutes": -30,
+//	                                     "action": "preset", "preset": "evening"}, {"cron": "0 23 * * *", "action": "blank"}]}
+func registerAPI(m *macaron.Macaron) {
+	m.Group("/api/v1", func() {
+		m.Get("/state", func(ctx *macaron.Context) string {
+			vars, colors := control.Values()
+			return writeJSON(ctx, state{Vars: vars, Colors: colors})
+		})
+		m.Put("/state", func(ctx *macaron.Context) string {
+			var body state
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if status, err := applyState(body); err != nil {
+				return writeError(ctx, status, err)
+			}
+			vars, colors := control.Values()
+			return writeJSON(ctx, state{Vars: vars, Colors: colors})
+		})
+
+		m.Get("/vars", func(ctx *macaron.Context) string {
+			vars, _ := control.Values()
+			return writeJSON(ctx, vars)
+		})
+		m.Patch("/vars", func(ctx *macaron.Context) string {
+			var vars map[string]float64
+			if err := readJSON(ctx, &vars); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if status, err := applyState(state{Vars: vars}); err != nil {
+				return writeError(ctx, status, err)
+			}
+			vars, _ = control.Values()
+			return writeJSON(ctx, vars)
+		})
+		m.Get("/vars/:name", func(ctx *macaron.Context) string {
+			name := ctx.Params(":name")
+			if !control.HasVar(name) {
+				return writeError(ctx, http.StatusNotFound, fmt.Errorf("no var named %s", name))
+			}
+			vars, _ := control.Values()
+			return writeJSON(ctx, value{Name: name, Value: vars[name]})
+		})
+		m.Put("/vars/:name", func(ctx *macaron.Context) string {
+			var body struct {
+				Value *float64 `json:"value"`
+			}
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if body.Value == nil {
+				return writeError(ctx, http.StatusBadRequest, fmt.Errorf("value is required"))
+			}
+			name := ctx.Params(":name")
+			if status, err := applyState(state{Vars: map[string]float64{name: *body.Value}}); err != nil {
+				return writeError(ctx, status, err)
+			}
+			vars, _ := control.Values()
+			return writeJSON(ctx, value{Name: name, Value: vars[name]})
+		})
+
+		m.Get("/colors", func(ctx *macaron.Context) string {
+			_, colors := control.Values()
+			return writeJSON(ctx, colors)
+		})
+		m.Patch("/colors", func(ctx *macaron.Context) string {
+			var colors map[string]string
+			if err := readJSON(ctx, &colors); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if status, err := applyState(state{Colors: colors}); err != nil {
+				return writeError(ctx, status, err)
+			}
+			_, colors = control.Values()
+			return writeJSON(ctx, colors)
+		})
+		m.Get("/colors/:name", func(ctx *macaron.Context) string {
+			name := ctx.Params(":name")
+			if !control.HasColor(name) {
+				return writeError(ctx, http.StatusNotFound, fmt.Errorf("no color named %s", name))
+			}
+			return writeJSON(ctx, value{Name: name, Value: control.GetColorHex(name)})
+		})
+		m.Put("/colors/:name", func(ctx *macaron.Context) string {
+			var body struct {
+				Value *string `json:"value"`
+			}
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if body.Value == nil {
+				return writeError(ctx, http.StatusBadRequest, fmt.Errorf("value is required"))
+			}
+			name := ctx.Params(":name")
+			if status, err := applyState(state{Colors: map[string]string{name: *body.Value}}); err != nil {
+				return writeError(ctx, status, err)
+			}
+			return writeJSON(ctx, value{Name: name, Value: control.GetColorHex(name)})
+		})
+
+		m.Get("/presets", func(ctx *macaron.Context) string {
+			list, err := presets.List()
+			if err != nil {
+				return writeError(ctx, http.StatusInternalServerError, err)
+			}
+			return writeJSON(ctx, list)
+		})
+		m.Get("/presets/:name", func(ctx *macaron.Context) string {
+			p, err := presets.Get(ctx.Params(":name"))
+			if err != nil {
+				return writeError(ctx, presetErrorStatus(err), err)
+			}
+			return writeJSON(ctx, p)
+		})
+		m.Put("/presets/:name", func(ctx *macaron.Context) string {
+			p := control.Snapshot(ctx.Params(":name"))
+			if err := presets.Save(p); err != nil {
+				return writeError(ctx, presetErrorStatus(err), err)
+			}
+			return writeJSON(ctx, p)
+		})
+		m.Post("/presets/:name/recall", func(ctx *macaron.Context) string {
+			p, err := presets.Get(ctx.Params(":name"))
+			if err != nil {
+				return writeError(ctx, presetErrorStatus(err), err)
+			}
+			if err := control.Show(p); err != nil {
+				return writeError(ctx, http.StatusUnprocessableEntity, err)
+			}
+			vars, colors := control.Values()
+			return writeJSON(ctx, state{Vars: vars, Colors: colors})
+		})
+		m.Patch("/presets/:name", func(ctx *macaron.Context) string {
+			var body struct {
+				Name string `json:"name"`
+			}
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if err := presets.Rename(ctx.Params(":name"), body.Name); err != nil {
+				return writeError(ctx, presetErrorStatus(err), err)
+			}
+			p, err := presets.Get(body.Name)
+			if err != nil {
+				return writeError(ctx, presetErrorStatus(err), err)
+			}
+			return writeJSON(ctx, p)
+		})
+		m.Delete("/presets/:name", func(ctx *macaron.Context) string {
+			if err := presets.Delete(ctx.Params(":name")); err != nil {
+				return writeError(ctx, presetErrorStatus(err), err)
+			}
+			ctx.Resp.WriteHeader(http.StatusNoContent)
+			return ""
+		})
+
+		m.Get("/animations", func(ctx *macaron.Context) string {
+			return writeJSON(ctx, animations{Current: animation.Current(), Animations: animation.Names(), Transition: animation.DefaultTransition})
+		})
+		m.Put("/animations/current", func(ctx *macaron.Context) string {
+			var body struct {
+				Name       string                `json:"name"`
+				Transition *animation.Transition `json:"transition"`
+			}
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			t := animation.DefaultTransition
+			if body.Transition != nil {
+				t = *body.Transition
+				if err := t.Check(); err != nil {
+					return writeError(ctx, http.StatusUnprocessableEntity, fmt.Errorf("%s: %v", t.Type, err))
+				}
+			}
+			if err := animation.SelectWith(body.Name, t); err != nil {
+				return writeError(ctx, http.StatusNotFound, fmt.Errorf("%s: %v", body.Name, err))
+			}
+			return writeJSON(ctx, animations{Current: animation.Current(), Animations: animation.Names(), Transition: animation.DefaultTransition})
+		})
+		m.Get("/animations/:name", func(ctx *macaron.Context) string {
+			info, err := animation.Describe(ctx.Params(":name"))
+			if err != nil {
+				return writeError(ctx, http.StatusNotFound, fmt.Errorf("%s: %v", ctx.Params(":name"), err))
+			}
+			return writeJSON(ctx, info)
+		})
+		m.Get("/params", func(ctx *macaron.Context) string {
+			return writeJSON(ctx, params{Animation: animation.Current(), Params: animation.Params()})
+		})
+
+		m.Get("/playlist", func(ctx *macaron.Context) string {
+			return writeJSON(ctx, playlist.Status())
+		})
+		m.Put("/playlist", func(ctx *macaron.Context) string {
+			var body struct {
+				Entries []animation.PlaylistEntry `json:"entries"`
+				Shuffle bool                      `json:"shuffle"`
+			}
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			for i, e := range body.Entries {
+				if err := playlist.CheckEntry(e); err != nil {
+					return writeError(ctx, http.StatusUnprocessableEntity, fmt.Errorf("entry %d: %v", i, err))
+				}
+			}
+			if err := playlist.Set(body.Entries, body.Shuffle); err != nil {
+				return writeError(ctx, http.StatusInternalServerError, err)
+			}
+			return writeJSON(ctx, playlist.Status())
+		})
+		m.Post("/playlist/:action", func(ctx *macaron.Context) string {
+			var err error
+			switch ctx.Params(":action") {
+			case "play":
+				err = playlist.Play()
+			case "pause":
+				err = playlist.Pause()
+			case "skip":
+				err = playlist.Skip()
+			default:
+				return writeError(ctx, http.StatusNotFound, fmt.Errorf("no playlist action %s, use play, pause or skip", ctx.Params(":action")))
+			}
+			if err == animation.ErrPlaylistEmpty {
+				return writeError(ctx, http.StatusConflict, err)
+			}
+			if err != nil {
+				return writeError(ctx, http.StatusInternalServerError, err)
+			}
+			return writeJSON(ctx, playlist.Status())
+		})
+
+		m.Get("/modulators", func(ctx *macaron.Context) string {
+			vars, hues := control.AllModulators()
+			return writeJSON(ctx, modulators{Vars: vars, Hues: hues})
+		})
+		m.Put("/modulators", func(ctx *macaron.Context) string {
+			var body modulators
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			for name, mod := range body.Vars {
+				if status, err := checkModulator(name, mod, control.HasVar); err != nil {
+					return writeError(ctx, status, err)
+				}
+			}
+			for name, mod := range body.Hues {
+				if status, err := checkModulator(name, mod, control.HasColor); err != nil {
+					return writeError(ctx, status, err)
+				}
+			}
+			if err := control.ReplaceModulators(body.Vars, body.Hues); err != nil {
+				return writeError(ctx, http.StatusUnprocessableEntity, err)
+			}
+			vars, hues := control.AllModulators()
+			return writeJSON(ctx, modulators{Vars: vars, Hues: hues})
+		})
+		m.Put("/modulators/vars/:name", func(ctx *macaron.Context) string {
+			var mod animation.Modulator
+			if err := readJSON(ctx, &mod); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			name := ctx.Params(":name")
+			if status, err := checkModulator(name, mod, control.HasVar); err != nil {
+				return writeError(ctx, status, err)
+			}
+			if err := control.SetModulator(name, mod); err != nil {
+				return writeError(ctx, http.StatusNotFound, err)
+			}
+			return writeJSON(ctx, mod)
+		})
+		m.Delete("/modulators/vars/:name", func(ctx *macaron.Context) string {
+			if err := control.RemoveModulator(ctx.Params(":name")); err != nil {
+				return writeError(ctx, http.StatusNotFound, err)
+			}
+			ctx.Resp.WriteHeader(http.StatusNoContent)
+			return ""
+		})
+		m.Put("/modulators/hues/:name", func(ctx *macaron.Context) string {
+			var mod animation.Modulator
+			if err := readJSON(ctx, &mod); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			name := ctx.Params(":name")
+			if status, err := checkModulator(name, mod, control.HasColor); err != nil {
+				return writeError(ctx, status, err)
+			}
+			if err := control.SetHueModulator(name, mod); err != nil {
+				return writeError(ctx, http.StatusNotFound, err)
+			}
+			return writeJSON(ctx, mod)
+		})
+		m.Delete("/modulators/hues/:name", func(ctx *macaron.Context) string {
+			if err := control.RemoveHueModulator(ctx.Params(":name")); err != nil {
+				return writeError(ctx, http.StatusNotFound, err)
+			}
+			ctx.Resp.WriteHeader(http.StatusNoContent)
+			return ""
+		})
+
+		m.Get("/store", func(ctx *macaron.Context) string {
+			store := make(map[string]map[string]interface{})
+			for _, ns := range animation.Namespaces() {
+				store[ns] = control.Store().Values(ns)
+			}
+			return writeJSON(ctx, store)
+		})
+		m.Get("/store/:namespace", func(ctx *macaron.Context) string {
+			ns := ctx.Params(":namespace")
+			if !hasNamespace(ns) {
+				return writeError(ctx, http.StatusNotFound, fmt.Errorf("no namespace named %s", ns))
+			}
+			return writeJSON(ctx, control.Store().Values(ns))
+		})
+		m.Put("/store/:namespace/:name", func(ctx *macaron.Context) string {
+			var body struct {
+				Value json.RawMessage `json:"value"`
+			}
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if body.Value == nil {
+				return writeError(ctx, http.StatusBadRequest, fmt.Errorf("value is required"))
+			}
+			ns, name := ctx.Params(":namespace"), ctx.Params(":name")
+			if err := control.SetJSON(ns, name, body.Value); err != nil {
+				if _, err := control.Store().Get(ns, name); err != nil {
+					return writeError(ctx, http.StatusNotFound, err)
+				}
+				return writeError(ctx, http.StatusUnprocessableEntity, err)
+			}
+			v, _ := control.Store().Get(ns, name)
+			return writeJSON(ctx, value{Name: name, Value: v})
+		})
+
+		m.Get("/changes", func(ctx *macaron.Context) string {
+			if ctx.Query("since") == "" {
+				return writeJSON(ctx, changes{Version: control.Version()})
+			}
+			since := uint64(ctx.QueryInt64("since"))
+			ch, cancel := control.SubscribeAll()
+			defer cancel()
+			timeout := time.NewTimer(changesTimeout)
+			defer timeout.Stop()
+			for control.Version() == since {
+				select {
+				case <-ch:
+				case <-timeout.C:
+					return writeJSON(ctx, changes{Version: since})
+				}
+			}
+			return writeJSON(ctx, changes{Version: control.Version()})
+		})
+
+		m.Get("/schedule", func(ctx *macaron.Context) string {
+			return writeJSON(ctx, scheduler.Status())
+		})
+		m.Put("/schedule", func(ctx *macaron.Context) string {
+			var body animation.Schedule
+			if err := readJSON(ctx, &body); err != nil {
+				return writeError(ctx, http.StatusBadRequest, err)
+			}
+			if err := scheduler.Check(body); err != nil {
+				return writeError(ctx, http.StatusUnprocessableEntity, err)
+			}
+			if err := scheduler.Set(body.Latitude, body.Longitude, body.Rules); err != nil {
+				return writeError(ctx, http.StatusInternalServerError, err)
+			}
+			return writeJSON(ctx, scheduler.Status())
+		})
+	})
+}
+
+func hasNamespace(ns string) bool {
+	for _, n := range animation.Namespaces() {
+		if n == ns {
+			return true
+		}
+	}
+	return false
+}
+
+func presetErrorStatus(err error) int {
+	switch err {
+	case animation.ErrPresetNotFound:
+		return http.StatusNotFound
+	case animation.ErrPresetExists:
+		return http.StatusConflict
+	case animation.ErrInvalidPresetName:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// checkModulator returns the HTTP status to respond with if the var or color doesn't exist or the
+// modulator is invalid.
+func checkModulator(name string, mod animation.Modulator, exists func(string) bool) (int, error) {
+	if !exists(name) {
+		return http.StatusNotFound, fmt.Errorf("nothing named %s to modulate", name)
+	}
+	if err := mod.Check(); err != nil {
+		return http.StatusUnprocessableEntity, fmt.Errorf("%s: %v", name, err)
+	}
+	return http.StatusOK, nil
+}
+
+// applyState validates every value in the state and then applies them together.
+// It returns the HTTP status to respond with if any of them is invalid.
+func applyState(s state) (int, error) {
+	for name := range s.Vars {
+		if !control.HasVar(name) {
+			return http.StatusNotFound, fmt.Errorf("no var named %s", name)
+		}
+	}
+	for name := range s.Colors {
+		if !control.HasColor(name) {
+			return http.StatusNotFound, fmt.Errorf("no color named %s", name)
+		}
+	}
+	if err := control.Update(s.Vars, s.Colors); err != nil {
+		return http.StatusUnprocessableEntity, err
+	}
+	return http.StatusOK, nil
+}
+
+func readJSON(ctx *macaron.Context, v interface{}) error {
+	body, err := ctx.Req.Body().Bytes()
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	return nil
+}
+
+func writeError(ctx *macaron.Context, status int, err error) string {
+	ctx.Header().Set("Content-Type", "application/json")
+	ctx.Resp.WriteHeader(status)
+	jsonBytes, _ := json.Marshal(apiError{Error: err.Error()})
+	return string(jsonBytes)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drichelson/ledicious/animation"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/macaron.v1"
+)
+
+func newTestAPI() *macaron.Macaron {
+	animation.Select("opensimplex")
+	control = animation.NewControl()
+	control.SetVar("speed", 0.3)
+	control.SetVar("brightness", 1.0)
+	control.SetColorHex("A", "ff00ff")
+	m := macaron.New()
+	registerAPI(m)
+	return m
+}
+
+func request(m *macaron.Macaron, method, path, body string) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	m.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestAPIGetState(t *testing.T) {
+	resp := request(newTestAPI(), "GET", "/api/v1/state", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{
+		"vars": {"speed": 0.3, "brightness": 1, "dither": 0, "varA": 0, "varB": 0.1, "varC": 0.9, "varD": 1},
+		"colors": {"A": "ff00ff", "B": "000000", "C": "000000", "D": "00084d"}}`, resp.Body.String())
+}
+
+func TestAPIPutVar(t *testing.T) {
+	m := newTestAPI()
+	resp := request(m, "PUT", "/api/v1/vars/speed", `{"value": 0.75}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"name": "speed", "value": 0.75}`, resp.Body.String())
+	assert.Equal(t, 0.75, control.GetVar("speed"))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, request(m, "PUT", "/api/v1/vars/speed", `{"value": 2}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(m, "PUT", "/api/v1/vars/speed", `{"value": "fast"}`).Code)
+	assert.Equal(t, http.StatusNotFound, request(m, "PUT", "/api/v1/vars/sped", `{"value": 0.5}`).Code)
+	assert.Equal(t, 0.75, control.GetVar("speed"))
+}
+
+func TestAPIPutColor(t *testing.T) {
+	m := newTestAPI()
+	resp := request(m, "PUT", "/api/v1/colors/A", `{"value": "#00FF00"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "00ff00", control.GetColorHex("A"))
+
+	resp = request(m, "PUT", "/api/v1/colors/A", `{"value": "green"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "6 digit hex color")
+}
+
+func TestAPIPatchIsAllOrNothing(t *testing.T) {
+	m := newTestAPI()
+	resp := request(m, "PATCH", "/api/v1/vars", `{"speed": 0.5, "brightness": -1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Equal(t, 0.3, control.GetVar("speed"))
+
+	resp = request(m, "PUT", "/api/v1/state", `{"vars": {"speed": 0.5}, "colors": {"A": "0000ff"}}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, 0.5, control.GetVar("speed"))
+	assert.Equal(t, "0000ff", control.GetColorHex("A"))
+}
+
+func TestAPIPresets(t *testing.T) {
+	m := newTestAPI()
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	presets, _ = animation.NewPresetStore(dir)
+
+	assert.Equal(t, http.StatusOK, request(m, "PUT", "/api/v1/presets/calm", "").Code)
+	control.SetVar("speed", 0.9)
+	assert.Equal(t, http.StatusOK, request(m, "POST", "/api/v1/presets/calm/recall", "").Code)
+	assert.Equal(t, 0.3, control.GetVar("speed"))
+
+	assert.Equal(t, http.StatusOK, request(m, "PATCH", "/api/v1/presets/calm", `{"name": "still"}`).Code)
+	assert.Equal(t, http.StatusNotFound, request(m, "GET", "/api/v1/presets/calm", "").Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, request(m, "PUT", "/api/v1/presets/..%2Fstate", "").Code)
+	assert.Equal(t, http.StatusNoContent, request(m, "DELETE", "/api/v1/presets/still", "").Code)
+
+	// The UI escapes just the name, as encodeURIComponent does.
+	assert.Equal(t, http.StatusOK, request(m, "PUT", "/api/v1/presets/Late%20night", "").Code)
+	assert.Equal(t, http.StatusOK, request(m, "POST", "/api/v1/presets/Late%20night/recall", "").Code)
+	assert.Equal(t, http.StatusNoContent, request(m, "DELETE", "/api/v1/presets/Late%20night", "").Code)
+}
+
+func TestAPIPlaylist(t *testing.T) {
+	m := newTestAPI()
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	presets, _ = animation.NewPresetStore(dir)
+	playlist, _ = animation.NewPlaylist(control, presets, filepath.Join(dir, "playlist.json"))
+
+	assert.Equal(t, http.StatusConflict, request(m, "POST", "/api/v1/playlist/play", "").Code)
+	resp := request(m, "PUT", "/api/v1/playlist", `{"entries": [{"preset": "calm", "seconds": 60}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "entry 0")
+
+	resp = request(m, "PUT", "/api/v1/playlist", `{"entries": [{"animation": "gradient-test", "seconds": 60}, {"animation": "brightness-test", "seconds": 60}]}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, http.StatusOK, request(m, "POST", "/api/v1/playlist/play", "").Code)
+	assert.Equal(t, "gradient-test", animation.Current())
+	assert.Equal(t, http.StatusOK, request(m, "POST", "/api/v1/playlist/skip", "").Code)
+	assert.Equal(t, "brightness-test", animation.Current())
+	assert.Equal(t, http.StatusNotFound, request(m, "POST", "/api/v1/playlist/rewind", "").Code)
+}
+
+func TestAPISchedule(t *testing.T) {
+	m := newTestAPI()
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	presets, _ = animation.NewPresetStore(dir)
+	scheduler, _ = animation.NewScheduler(control, presets, filepath.Join(dir, "schedule.json"))
+
+	resp := request(m, "PUT", "/api/v1/schedule", `{"latitude": 51.5, "longitude": -0.1, "rules": [{"cron": "0 25 * * *", "action": "blank"}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+	assert.Contains(t, resp.Body.String(), "rule 0")
+
+	resp = request(m, "PUT", "/api/v1/schedule", `{"latitude": 51.5, "longitude": -0.1, "rules": [{"sun": "sunset", "action": "brightness", "brightness": 0.5}]}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"sunset":`)
+	assert.Len(t, scheduler.Status().Next, 1)
+	assert.NotNil(t, scheduler.Status().Next[0])
+}
+
+func TestAPIModulators(t *testing.T) {
+	m := newTestAPI()
+	assert.Equal(t, http.StatusNotFound, request(m, "PUT", "/api/v1/modulators/vars/sped", `{"shape": "sine", "rate": 1, "depth": 0.1}`).Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, request(m, "PUT", "/api/v1/modulators/vars/speed", `{"shape": "sine", "rate": 0}`).Code)
+	assert.Equal(t, http.StatusOK, request(m, "PUT", "/api/v1/modulators/vars/speed", `{"shape": "saw", "rate": 1, "depth": 0.1}`).Code)
+	assert.Equal(t, http.StatusOK, request(m, "PUT", "/api/v1/modulators/hues/A", `{"shape": "triangle", "rate": 0.1, "depth": 0.5}`).Code)
+
+	resp := request(m, "GET", "/api/v1/modulators", "")
+	assert.JSONEq(t, `{"vars": {"speed": {"shape": "saw", "rate": 1, "depth": 0.1, "phase": 0}},
+		"hues": {"A": {"shape": "triangle", "rate": 0.1, "depth": 0.5, "phase": 0}}}`, resp.Body.String())
+	assert.JSONEq(t, `{"name": "speed", "value": 0.3}`, request(m, "GET", "/api/v1/vars/speed", "").Body.String())
+
+	assert.Equal(t, http.StatusNoContent, request(m, "DELETE", "/api/v1/modulators/vars/speed", "").Code)
+	assert.Equal(t, http.StatusOK, request(m, "PUT", "/api/v1/modulators", `{"vars": {}, "hues": {}}`).Code)
+	vars, hues := control.AllModulators()
+	assert.Empty(t, vars)
+	assert.Empty(t, hues)
+}
+
+func TestAPIStore(t *testing.T) {
+	m := newTestAPI()
+	resp := request(m, "GET", "/api/v1/store/global", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"brightness": 1, "dither": 0}`, resp.Body.String())
+
+	resp = request(m, "PUT", "/api/v1/store/opensimplex/A", `{"value": "#00FF00"}`)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"name": "A", "value": "00ff00"}`, resp.Body.String())
+	assert.Equal(t, "00ff00", control.GetColorHex("A"))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, request(m, "PUT", "/api/v1/store/opensimplex/speed", `{"value": "fast"}`).Code)
+	assert.Equal(t, http.StatusNotFound, request(m, "PUT", "/api/v1/store/global/speed", `{"value": 0.5}`).Code)
+	assert.Equal(t, http.StatusNotFound, request(m, "GET", "/api/v1/store/nope", "").Code)
+}
+
+func TestAPIChanges(t *testing.T) {
+	m := newTestAPI()
+	version := control.Version()
+	resp := request(m, "GET", "/api/v1/changes", "")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"version": %d}`, version), resp.Body.String())
+
+	done := make(chan string)
+	go func() {
+		done <- request(m, "GET", fmt.Sprintf("/api/v1/changes?since=%d", version), "").Body.String()
+	}()
+	control.SetVar("speed", 0.9)
+	assert.JSONEq(t, fmt.Sprintf(`{"version": %d}`, version+1), <-done)
+
+	changesTimeout = 10 * time.Millisecond
+	defer func() { changesTimeout = 30 * time.Second }()
+	resp = request(m, "GET", fmt.Sprintf("/api/v1/changes?since=%d", version+1), "")
+	assert.JSONEq(t, fmt.Sprintf(`{"version": %d}`, version+1), resp.Body.String())
+}
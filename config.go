@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/drichelson/ledicious/animation"
+	"github.com/drichelson/ledicious/output"
+	"github.com/drichelson/ledicious/usb"
+)
+
+// config is read from a JSON file at startup. Everything is optional: without a file
+// frames go to a single Teensy, as they always have. Set "teensy" to null to turn it off.
+type config struct {
+	// Teensy sends frames to the Teensy over USB.
+	Teensy *output.TeensyConfig `json:"teensy"`
+	// Teensys splits the pixels across several Teensys, matched by USB serial number. The default
+	// Teensy is left out when these are set, and setting both is an error.
+	Teensys []output.TeensyConfig `json:"teensys"`
+	// Globe writes frames to PNG files, for reviewing animations without the globe.
+	Globe *output.GlobeConfig `json:"globe"`
+	// OPC sends frames to an Open Pixel Control server such as fadecandy.
+	OPC *output.OPCConfig `json:"opc"`
+	// SACN sends frames to E1.31 pixel controllers.
+	SACN *output.SACNConfig `json:"sacn"`
+	// ArtNet sends frames to Art-Net nodes.
+	ArtNet *output.ArtNetConfig `json:"artnet"`
+	// DDP sends frames to ESP32/WLED controllers.
+	DDP *output.DDPConfig `json:"ddp"`
+
+	// Correction sets the per-channel gamma, white point and minimum visible level for every output.
+	Correction *usb.CorrectionConfig `json:"correction"`
+	// Dither turns temporal dithering on at startup. It can be switched with the "dither" var.
+	Dither bool `json:"dither"`
+	// Power sets the budget that frames are dimmed to stay within.
+	Power usb.PowerConfig `json:"power"`
+	// FPS is the target frame rate. It defaults to animation.DefaultFPS.
+	FPS float64 `json:"fps"`
+	// State is the file the vars and colors are saved to, so they survive a restart.
+	State string `json:"state"`
+	// Animation is the animation shown at startup, see animation.Names.
+	Animation string `json:"animation"`
+	// Transition is how animations hand over when one is selected, e.g. {"type": "wipe-latitude", "seconds": 3}.
+	Transition *animation.Transition `json:"transition"`
+	// Presets is the directory presets are saved in, one JSON file each.
+	Presets string `json:"presets"`
+	// Playlist is the file the playlist is saved to, so it carries on playing after a restart.
+	Playlist string `json:"playlist"`
+	// Schedule is the file the schedule's location and rules are saved to.
+	Schedule string `json:"schedule"`
+}
+
+func defaultConfig() config {
+	return config{
+		Teensy:   &output.TeensyConfig{},
+		State:    "state.json",
+		Presets:  "presets",
+		Playlist: "playlist.json",
+		Schedule: "schedule.json",
+	}
+}
+
+func loadConfig(path string) (config, error) {
+	c := defaultConfig()
+	bytes, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(bytes, &c); err != nil {
+		return c, err
+	}
+	if len(c.Teensys) > 0 {
+		// The default Teensy would claim whichever Teensy is found first and send it every pixel.
+		var keys map[string]json.RawMessage
+		json.Unmarshal(bytes, &keys)
+		if teensy, ok := keys["teensy"]; ok && string(teensy) != "null" {
+			return c, fmt.Errorf("set teensy or teensys, not both")
+		}
+		c.Teensy = nil
+	}
+	return c, c.check(len(animation.Positions()))
+}
+
+// check returns an error if any output is misconfigured for a frame of pixelCount pixels.
+func (c config) check(pixelCount int) error {
+	type pixelRange struct {
+		name       string
+		start, end int
+	}
+	ranges := make([]pixelRange, 0, len(c.Teensys))
+	for _, teensy := range c.Teensys {
+		name := "teensy " + teensy.Serial
+		if err := teensy.Check(pixelCount); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		start, end := teensy.Range(pixelCount)
+		for _, r := range ranges {
+			if start < r.end && r.start < end {
+				return fmt.Errorf("%s and %s drive some of the same pixels", r.name, name)
+			}
+		}
+		ranges = append(ranges, pixelRange{name, start, end})
+	}
+	if c.SACN != nil {
+		if err := c.SACN.Check(); err != nil {
+			return fmt.Errorf("sacn: %v", err)
+		}
+	}
+	if c.ArtNet != nil {
+		if err := c.ArtNet.Check(pixelCount); err != nil {
+			return fmt.Errorf("artnet: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c config) outputs() []output.Output {
+	outputs := make([]output.Output, 0)
+	if c.Teensy != nil {
+		outputs = append(outputs, output.NewTeensy(*c.Teensy))
+	}
+	for _, teensy := range c.Teensys {
+		outputs = append(outputs, output.NewTeensy(teensy))
+	}
+	if c.Globe != nil {
+		outputs = append(outputs, output.NewGlobe(*c.Globe, animation.Positions()))
+	}
+	if c.OPC != nil {
+		outputs = append(outputs, output.NewOPC(*c.OPC))
+	}
+	if c.SACN != nil {
+		outputs = append(outputs, output.NewSACN(*c.SACN))
+	}
+	if c.ArtNet != nil {
+		outputs = append(outputs, output.NewArtNet(*c.ArtNet, len(animation.Positions())))
+	}
+	if c.DDP != nil {
+		outputs = append(outputs, output.NewDDP(*c.DDP))
+	}
+	return outputs
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/drichelson/ledicious/animation"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestConfig(t *testing.T, json string) (config, error) {
+	dir, err := ioutil.TempDir("", "ledicious")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(json), 0644))
+	return loadConfig(path)
+}
+
+func TestConfigTeensys(t *testing.T) {
+	c, err := loadTestConfig(t, `{}`)
+	assert.NoError(t, err)
+	assert.NotNil(t, c.Teensy, "one Teensy by default")
+
+	c, err = loadTestConfig(t, `{"teensys": [{"serial": "A", "count": 600}, {"serial": "B", "start": 600}]}`)
+	assert.NoError(t, err)
+	assert.Nil(t, c.Teensy, "the default Teensy is left out")
+	assert.Len(t, c.outputs(), 2)
+
+	_, err = loadTestConfig(t, `{"teensy": {}, "teensys": [{"serial": "A"}]}`)
+	assert.Error(t, err)
+	_, err = loadTestConfig(t, `{"teensys": [{"serial": "A", "count": 601}, {"serial": "B", "start": 600}]}`)
+	assert.Error(t, err, "overlapping ranges")
+	pixelCount := len(animation.Positions())
+	_, err = loadTestConfig(t, `{"teensys": [{"serial": "A", "start": 10, "count": `+strconv.Itoa(pixelCount)+`}]}`)
+	assert.Error(t, err, "past the end of the frame")
+	_, err = loadTestConfig(t, `{"teensys": [{"serial": "A", "start": -1}]}`)
+	assert.Error(t, err)
+}
+
+func TestConfigChecksArtNet(t *testing.T) {
+	_, err := loadTestConfig(t, `{"artnet": {"address": "10.0.0.2", "universes": [{"start": 0, "count": 200}]}}`)
+	assert.Error(t, err)
+	_, err = loadTestConfig(t, `{"artnet": {"address": "10.0.0.2", "universes": [{"start": 0, "count": 170, "subnet": 1}]}}`)
+	assert.NoError(t, err)
+}
@@ -1,23 +1,60 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/drichelson/ledicious/animation"
+	"github.com/drichelson/ledicious/output"
+	"github.com/drichelson/ledicious/telemetry"
+	"github.com/drichelson/ledicious/usb"
+	"github.com/launchdarkly/go-metrics"
 	"gopkg.in/macaron.v1"
 )
 
+// saveDebounce is how long control changes are collected before the state is written to disk.
+const saveDebounce = 1 * time.Second
+
 var (
-	control = animation.NewControl()
-	wowLog  log.Logger
+	control    = animation.NewControl()
+	fanout     *output.Fanout
+	presets    *animation.PresetStore
+	playlist   *animation.Playlist
+	scheduler  *animation.Scheduler
+	limiter    *usb.PowerLimiter
+	wowLog     log.Logger
+	configPath = flag.String("config", "ledicious.json", "path to the JSON config file")
 )
 
 func main() {
 	log.SetFlags(log.Ltime | log.Lmicroseconds | log.Lshortfile)
 	wowLog.SetFlags(log.Ltime | log.Ldate)
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Error loading config %s: %v", *configPath, err)
+	}
+	if cfg.Correction != nil {
+		usb.SetCorrection(*cfg.Correction)
+	}
+	limiter = usb.NewPowerLimiter(cfg.Power)
+	fanout = output.NewFanout(cfg.outputs()...)
+	fanout.SetPowerLimiter(limiter)
+	metrics.Register("outputs.connected", metrics.NewFunctionalGauge(func() int64 {
+		connected := int64(0)
+		for _, status := range fanout.Statuses() {
+			if status.Connected {
+				connected++
+			}
+		}
+		return connected
+	}))
 
 	//create your file with desired read/write permissions
 	f, err := os.OpenFile("wowLog.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
@@ -27,19 +64,48 @@ func main() {
 	defer f.Close()
 	wowLog.SetOutput(f)
 
-	control.SetVar("varA", 0.5)
-	control.SetVar("varB", 0.5)
-	control.SetVar("varC", 0.5)
-	control.SetVar("varD", 0.5)
-	control.SetVar("brightness", 1.0)
-	control.SetVar("speed", 0.3)
+	if cfg.Transition != nil {
+		if err := cfg.Transition.Check(); err != nil {
+			log.Fatalf("Error in transition %s: %v", cfg.Transition.Type, err)
+		}
+		animation.DefaultTransition = *cfg.Transition
+	}
+	if cfg.Animation != "" {
+		if err := animation.Select(cfg.Animation); err != nil {
+			log.Fatalf("Error selecting animation %s, choose one of %v: %v", cfg.Animation, animation.Names(), err)
+		}
+	}
+	// Saved values win over the params' defaults. The animation is selected first, so a state file
+	// from before params had namespaces is restored into the configured animation's.
+	if err := control.LoadFile(cfg.State); err != nil {
+		log.Printf("Error restoring control state from %s, using defaults: %v", cfg.State, err)
+	}
+	go control.Persist(cfg.State, saveDebounce, nil)
+	metrics.Register("control.version", metrics.NewFunctionalGauge(func() int64 {
+		return int64(control.Version())
+	}))
+	presets, err = animation.NewPresetStore(cfg.Presets)
+	if err != nil {
+		log.Fatalf("Error opening preset directory %s: %v", cfg.Presets, err)
+	}
+	// A playlist that was playing before the restart takes over from the animation chosen above.
+	playlist, err = animation.NewPlaylist(control, presets, cfg.Playlist)
+	if err != nil {
+		log.Printf("Error restoring playlist from %s, starting with an empty one: %v", cfg.Playlist, err)
+	}
+	go playlist.Run()
+	scheduler, err = animation.NewScheduler(control, presets, cfg.Schedule)
+	if err != nil {
+		log.Printf("Error restoring schedule from %s, starting with an empty one: %v", cfg.Schedule, err)
+	}
+	go scheduler.Run()
 
-	control.SetColorHex("A", "ff00FF")
-	control.SetColorHex("B", "ff00FF")
-	control.SetColorHex("C", "ff00FF")
-	control.SetColorHex("D", "ff00FF")
+	if cfg.Dither {
+		control.DefaultVar("dither", 1.0)
+	}
 
 	m := macaron.Classic()
+	m.Use(httpMetrics)
 	m.Use(macaron.Static("assets",
 		macaron.StaticOptions{
 			// Prefix is the optional prefix used to serve the static directory content. Default is empty string.
@@ -50,6 +116,9 @@ func main() {
 			IndexFile: "index.html",
 		}))
 
+	registerAPI(m)
+
+	// The routes below predate /api/v1 and are kept for the current page and old bookmarks.
 	m.Get("/wow", func(ctx *macaron.Context) string {
 		wowLog.Println(ctx.Req.URL.RawQuery)
 		return ""
@@ -61,6 +130,9 @@ func main() {
 	m.Get("/brightness", func(ctx *macaron.Context) string {
 		return getVar(ctx, "brightness")
 	})
+	m.Get("/dither", func(ctx *macaron.Context) string {
+		return getVar(ctx, "dither")
+	})
 	m.Get("/varA", func(ctx *macaron.Context) string {
 		return getVar(ctx, "varA")
 	})
@@ -86,8 +158,45 @@ func main() {
 	m.Get("/colorD", func(ctx *macaron.Context) string {
 		return getColor(ctx, "D")
 	})
+	m.Get("/power", func(ctx *macaron.Context) string {
+		return writeJSON(ctx, limiter.Stats())
+	})
+	m.Get("/outputs", func(ctx *macaron.Context) string {
+		return writeJSON(ctx, fanout.Statuses())
+	})
+	m.Get("/metrics", func(ctx *macaron.Context) {
+		ctx.Resp.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := telemetry.WritePrometheus(ctx.Resp, metrics.DefaultRegistry); err != nil {
+			log.Printf("Error writing metrics: %v", err)
+		}
+	})
+	m.Get("/stats", func(ctx *macaron.Context) string {
+		return writeJSON(ctx, struct {
+			Frames animation.FrameStats `json:"frames"`
+			Dither usb.DitherStats      `json:"dither"`
+		}{animation.Stats(), usb.Dither()})
+	})
 	go m.Run()
-	animation.Start(control)
+	animation.Start(control, fanout, cfg.FPS)
+}
+
+var (
+	httpTimer        = metrics.GetOrRegisterTimer("http.requests", metrics.DefaultRegistry)
+	httpClientErrors = metrics.GetOrRegisterCounter("http.responses.4xx", metrics.DefaultRegistry)
+	httpServerErrors = metrics.GetOrRegisterCounter("http.responses.5xx", metrics.DefaultRegistry)
+)
+
+// httpMetrics times every request and counts error responses.
+func httpMetrics(ctx *macaron.Context) {
+	start := time.Now()
+	ctx.Next()
+	httpTimer.UpdateSince(start)
+	switch status := ctx.Resp.Status(); {
+	case status >= 500:
+		httpServerErrors.Inc(1)
+	case status >= 400:
+		httpClientErrors.Inc(1)
+	}
 }
 
 // Generic handler for getting/setting vars.
@@ -100,24 +209,41 @@ func getVar(ctx *macaron.Context, varName string) string {
 		return "{\"state\": \"" + strconv.Itoa(int(control.GetVar(varName)*1000.0)) + "\"}"
 	}
 	newVal, err := strconv.Atoi(newValString)
+	if err == nil {
+		err = control.SetVar(varName, float64(newVal)/1000.0)
+	}
 	if err != nil {
 		ctx.Resp.WriteHeader(http.StatusBadRequest)
 		return "not a number!"
 	}
-	control.SetVar(varName, float64(newVal)/1000.0)
 	//fmt.Printf("new value: %s %d\n", varName, newVal)
-	//log.Println(control.State())
 	return "{\"state\": \"" + newValString + "\"}"
 }
 
+func writeJSON(ctx *macaron.Context, v interface{}) string {
+	ctx.Header().Set("Content-Type", "application/json")
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		ctx.Resp.WriteHeader(http.StatusInternalServerError)
+		return err.Error()
+	}
+	return string(jsonBytes)
+}
+
 func getColor(ctx *macaron.Context, varName string) string {
 	ctx.Header().Set("Content-Type", "application/json")
 	newVal := ctx.Query("state")
 	if newVal == "" {
 		return "{\"state\": \"" + control.GetColorHex(varName) + "\"}"
 	}
-	control.SetColorHex(varName, newVal)
+	newVal, err := animation.CheckColorHex(varName, newVal)
+	if err == nil {
+		err = control.SetColorHex(varName, newVal)
+	}
+	if err != nil {
+		ctx.Resp.WriteHeader(http.StatusBadRequest)
+		return "not a color!"
+	}
 	//fmt.Printf("new color: %s %s\n", varName, newVal)
-	//log.Println(control.State())
 	return "{\"state\": \"" + newVal + "\"}"
 }
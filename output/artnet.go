@@ -0,0 +1,197 @@
+package output
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+const (
+	artNetPort              = "6454"
+	artNetProtocolVersion   = 14
+	artNetOpDmx             = 0x5000
+	artNetOpSync            = 0x5200
+	artNetDmxHeaderLength   = 18
+	artNetSyncLength        = 14
+	artNetPixelsPerUniverse = 170
+	artNetMaxNet            = 127
+	artNetMaxSubnet         = 15
+	artNetMaxUniverse       = 15
+)
+
+var artNetID = []byte("Art-Net\x00")
+
+type ArtNetConfig struct {
+	// Address is where packets go unless a universe has its own. Port defaults to 6454.
+	Address string `json:"address"`
+	// Universes maps pixel index ranges to port-addresses. Without any, the pixels
+	// are split into 170 pixel blocks starting at net 0, subnet 0, universe 0.
+	Universes []ArtNetUniverse `json:"universes"`
+}
+
+type ArtNetUniverse struct {
+	// Start is the index of the first pixel sent to this universe.
+	Start int `json:"start"`
+	// Count of pixels, at most 170.
+	Count    int    `json:"count"`
+	Net      uint8  `json:"net"`
+	Subnet   uint8  `json:"subnet"`
+	Universe uint8  `json:"universe"`
+	Address  string `json:"address"`
+}
+
+func (u ArtNetUniverse) String() string {
+	return fmt.Sprintf("%d:%d:%d", u.Net, u.Subnet, u.Universe)
+}
+
+// Check returns an error if a universe's pixels don't fit in a frame of pixelCount pixels or in one
+// DMX universe, or its port-address is out of range.
+func (c ArtNetConfig) Check(pixelCount int) error {
+	for _, u := range c.Universes {
+		if u.Start < 0 || u.Count < 0 || u.Count > artNetPixelsPerUniverse {
+			return fmt.Errorf("universe %s: start must not be negative and count must be between 0 and %d, got %d and %d",
+				u, artNetPixelsPerUniverse, u.Start, u.Count)
+		}
+		if u.Start+u.Count > pixelCount {
+			return fmt.Errorf("universe %s: pixels %d to %d are outside the %d pixels of a frame", u, u.Start, u.Start+u.Count-1, pixelCount)
+		}
+		if u.Net > artNetMaxNet || u.Subnet > artNetMaxSubnet || u.Universe > artNetMaxUniverse {
+			return fmt.Errorf("universe %s: net must be at most %d, and subnet and universe at most %d",
+				u, artNetMaxNet, artNetMaxSubnet)
+		}
+	}
+	return nil
+}
+
+// ArtNet sends frames to Art-Net nodes as ArtDmx packets, followed by an ArtSync.
+type ArtNet struct {
+	tracker
+	encoder   usb.Encoder
+	config    ArtNetConfig
+	conn      net.PacketConn
+	addrs     []*net.UDPAddr
+	syncAddrs []*net.UDPAddr
+	sequences []byte
+}
+
+// NewArtNet returns an Art-Net output. The config must have passed Check.
+func NewArtNet(config ArtNetConfig, pixelCount int) *ArtNet {
+	if len(config.Universes) == 0 {
+		config.Universes = defaultArtNetUniverses(pixelCount)
+	}
+	return &ArtNet{
+		tracker:   newTracker("artnet " + config.Address),
+		config:    config,
+		sequences: make([]byte, len(config.Universes)),
+	}
+}
+
+// defaultArtNetUniverses splits pixelCount pixels into consecutive universes.
+func defaultArtNetUniverses(pixelCount int) []ArtNetUniverse {
+	universes := make([]ArtNetUniverse, 0)
+	for start, portAddress := 0, 0; start < pixelCount; start, portAddress = start+artNetPixelsPerUniverse, portAddress+1 {
+		count := artNetPixelsPerUniverse
+		if start+count > pixelCount {
+			count = pixelCount - start
+		}
+		universes = append(universes, ArtNetUniverse{
+			Start:    start,
+			Count:    count,
+			Net:      uint8(portAddress >> 8),
+			Subnet:   uint8(portAddress >> 4 & 0x0f),
+			Universe: uint8(portAddress & 0x0f),
+		})
+	}
+	return universes
+}
+
+func (a *ArtNet) Open() error {
+	a.addrs = make([]*net.UDPAddr, len(a.config.Universes))
+	a.syncAddrs = make([]*net.UDPAddr, 0)
+	seen := make(map[string]bool)
+	for i, u := range a.config.Universes {
+		address := u.Address
+		if address == "" {
+			address = a.config.Address
+		}
+		if address == "" {
+			return a.opened(fmt.Errorf("no address for universe %s", u))
+		}
+		addr, err := resolveUDP(address, artNetPort)
+		if err != nil {
+			return a.opened(err)
+		}
+		a.addrs[i] = addr
+		if !seen[addr.String()] {
+			seen[addr.String()] = true
+			a.syncAddrs = append(a.syncAddrs, addr)
+		}
+	}
+	var err error
+	a.conn, err = net.ListenPacket("udp4", ":0")
+	return a.opened(err)
+}
+
+func (a *ArtNet) Write(renderPkg usb.RenderPackage) error {
+	rgb := a.encoder.RGB(renderPkg)
+	for i, u := range a.config.Universes {
+		start, end := u.Start*3, (u.Start+u.Count)*3
+		if start > len(rgb) {
+			start = len(rgb)
+		}
+		if end > len(rgb) {
+			end = len(rgb)
+		}
+		a.sequences[i]++
+		if a.sequences[i] == 0 { // 0 disables sequencing on the receiver
+			a.sequences[i] = 1
+		}
+		if _, err := a.conn.WriteTo(artDmxPacket(u, a.sequences[i], rgb[start:end]), a.addrs[i]); err != nil {
+			return a.wrote(err)
+		}
+		a.count(u.String())
+	}
+	for _, addr := range a.syncAddrs {
+		if _, err := a.conn.WriteTo(artSyncPacket(), addr); err != nil {
+			return a.wrote(err)
+		}
+	}
+	return a.wrote(nil)
+}
+
+func (a *ArtNet) Close() error {
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+func artDmxPacket(u ArtNetUniverse, sequence byte, channels []byte) []byte {
+	length := len(channels)
+	if length%2 == 1 { // the DMX length must be even
+		length++
+	}
+	packet := make([]byte, artNetDmxHeaderLength+length)
+	copy(packet, artNetID)
+	binary.LittleEndian.PutUint16(packet[8:], artNetOpDmx)
+	binary.BigEndian.PutUint16(packet[10:], artNetProtocolVersion)
+	packet[12] = sequence
+	packet[13] = 0 // physical
+	packet[14] = u.Subnet<<4 | u.Universe&0x0f
+	packet[15] = u.Net & 0x7f
+	binary.BigEndian.PutUint16(packet[16:], uint16(length))
+	copy(packet[18:], channels)
+	return packet
+}
+
+func artSyncPacket() []byte {
+	packet := make([]byte, artNetSyncLength)
+	copy(packet, artNetID)
+	binary.LittleEndian.PutUint16(packet[8:], artNetOpSync)
+	binary.BigEndian.PutUint16(packet[10:], artNetProtocolVersion)
+	return packet
+}
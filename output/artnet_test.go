@@ -0,0 +1,67 @@
+package output
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtNetDefaultUniverses(t *testing.T) {
+	universes := defaultArtNetUniverses(testPixelCount)
+	assert.Len(t, universes, 8)
+	assert.Equal(t, ArtNetUniverse{Start: 1190, Count: 10, Universe: 7}, universes[7])
+	assert.Equal(t, "1:2:3", ArtNetUniverse{Net: 1, Subnet: 2, Universe: 3}.String())
+}
+
+func TestArtNetSendsMappedUniverses(t *testing.T) {
+	listener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	a := NewArtNet(ArtNetConfig{
+		Address: listener.LocalAddr().String(),
+		Universes: []ArtNetUniverse{
+			{Start: 0, Count: 3, Net: 1, Subnet: 2, Universe: 3},
+			{Start: 3, Count: 1, Universe: 9},
+		},
+	}, 4)
+	assert.NoError(t, a.Open())
+	defer a.Close()
+	pixels := []colorful.Color{{}, {}, {}, {B: 1.0}}
+	assert.NoError(t, a.Write(usb.RenderPackage{Pixels: pixels, Brightness: 1.0}))
+
+	packets := readPackets(t, listener, 3)
+	first, second, sync := packets[0], packets[1], packets[2]
+	assert.Equal(t, "Art-Net\x00", string(first[:8]))
+	assert.Equal(t, uint16(artNetOpDmx), binary.LittleEndian.Uint16(first[8:]))
+	assert.Equal(t, byte(0x23), first[14])
+	assert.Equal(t, byte(1), first[15])
+	assert.Equal(t, uint16(10), binary.BigEndian.Uint16(first[16:]), "odd lengths are padded")
+	assert.Equal(t, byte(9), second[14])
+	assert.Equal(t, []byte{0, 0, 255}, second[18:21])
+	assert.Equal(t, uint16(artNetOpSync), binary.LittleEndian.Uint16(sync[8:]))
+
+	counters := a.Status().Counters
+	assert.Equal(t, uint64(1), counters["1:2:3"])
+	assert.Equal(t, uint64(1), counters["0:0:9"])
+}
+
+func TestArtNetCheck(t *testing.T) {
+	check := func(u ArtNetUniverse) error {
+		return ArtNetConfig{Universes: []ArtNetUniverse{u}}.Check(1200)
+	}
+	assert.NoError(t, ArtNetConfig{}.Check(1200))
+	assert.NoError(t, ArtNetConfig{Universes: defaultArtNetUniverses(1200)}.Check(1200))
+	assert.NoError(t, check(ArtNetUniverse{Start: 1030, Count: 170, Net: 127, Subnet: 15, Universe: 15}))
+	assert.Error(t, check(ArtNetUniverse{Count: -1}))
+	assert.Error(t, check(ArtNetUniverse{Start: -1, Count: 10}))
+	assert.Error(t, check(ArtNetUniverse{Count: 171}), "more than one DMX universe")
+	assert.Error(t, check(ArtNetUniverse{Start: 1100, Count: 170}), "past the end of the frame")
+	assert.Error(t, check(ArtNetUniverse{Count: 10, Subnet: 16}))
+	assert.Error(t, check(ArtNetUniverse{Count: 10, Universe: 16}))
+	assert.Error(t, check(ArtNetUniverse{Count: 10, Net: 128}))
+}
@@ -0,0 +1,99 @@
+package output
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+const (
+	ddpPort          = "4048"
+	ddpHeaderLength  = 10
+	ddpMaxDataLength = 1440 // 480 RGB pixels, keeps packets under a typical MTU
+	ddpVersion1      = 0x40
+	ddpPush          = 0x01
+	ddpTypeRGB24     = 0x0b
+	ddpDefaultOutput = 1
+)
+
+type DDPConfig struct {
+	// Address of the controller, e.g. "wled-globe.local". The port defaults to 4048.
+	Address string `json:"address"`
+	// Offset is the index of the receiver's pixel that our first pixel lands on.
+	Offset int `json:"offset"`
+	// Destination is the DDP destination ID. Defaults to 1, the default output device.
+	Destination uint8 `json:"destination"`
+}
+
+// DDP sends frames with the Distributed Display Protocol (http://www.3waylabs.com/ddp/),
+// as supported by ESP32/WLED controllers. The push flag is only set on the last
+// packet of a frame so the controller shows the whole frame at once.
+type DDP struct {
+	tracker
+	encoder  usb.Encoder
+	config   DDPConfig
+	conn     net.PacketConn
+	addr     *net.UDPAddr
+	sequence byte
+}
+
+func NewDDP(config DDPConfig) *DDP {
+	if config.Destination == 0 {
+		config.Destination = ddpDefaultOutput
+	}
+	return &DDP{
+		tracker: newTracker("ddp " + config.Address),
+		config:  config,
+	}
+}
+
+func (d *DDP) Open() error {
+	var err error
+	d.addr, err = resolveUDP(d.config.Address, ddpPort)
+	if err != nil {
+		return d.opened(err)
+	}
+	d.conn, err = net.ListenPacket("udp4", ":0")
+	return d.opened(err)
+}
+
+func (d *DDP) Write(renderPkg usb.RenderPackage) error {
+	rgb := d.encoder.RGB(renderPkg)
+	d.sequence = d.sequence%15 + 1 // 1-15, 0 means sequencing isn't used
+	for start := 0; start < len(rgb); start += ddpMaxDataLength {
+		end := start + ddpMaxDataLength
+		if end > len(rgb) {
+			end = len(rgb)
+		}
+		packet := ddpPacket(d.config, d.sequence, d.config.Offset*3+start, rgb[start:end], end == len(rgb))
+		if _, err := d.conn.WriteTo(packet, d.addr); err != nil {
+			return d.wrote(err)
+		}
+	}
+	return d.wrote(nil)
+}
+
+func (d *DDP) Close() error {
+	if d.conn == nil {
+		return nil
+	}
+	err := d.conn.Close()
+	d.conn = nil
+	return err
+}
+
+func ddpPacket(config DDPConfig, sequence byte, offset int, data []byte, push bool) []byte {
+	packet := make([]byte, ddpHeaderLength+len(data))
+	packet[0] = ddpVersion1
+	if push {
+		packet[0] |= ddpPush
+	}
+	packet[1] = sequence
+	packet[2] = ddpTypeRGB24
+	packet[3] = config.Destination
+	binary.BigEndian.PutUint32(packet[4:], uint32(offset))
+	binary.BigEndian.PutUint16(packet[8:], uint16(len(data)))
+	copy(packet[ddpHeaderLength:], data)
+	return packet
+}
@@ -0,0 +1,40 @@
+package output
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDDPPushesLastPacket(t *testing.T) {
+	listener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	d := NewDDP(DDPConfig{Address: listener.LocalAddr().String(), Offset: 100})
+	assert.NoError(t, d.Open())
+	defer d.Close()
+	pixels := make([]colorful.Color, testPixelCount)
+	pixels[testPixelCount-1] = colorful.Color{R: 1.0}
+	assert.NoError(t, d.Write(usb.RenderPackage{Pixels: pixels, Brightness: 1.0}))
+
+	packets := readPackets(t, listener, 3)
+	expectedLengths := []int{1440, 1440, 720}
+	for i, packet := range packets {
+		assert.Equal(t, byte(1), packet[1], "sequence")
+		assert.Equal(t, byte(ddpTypeRGB24), packet[2])
+		assert.Equal(t, byte(ddpDefaultOutput), packet[3])
+		assert.Equal(t, uint32(300+i*1440), binary.BigEndian.Uint32(packet[4:]))
+		assert.Equal(t, uint16(expectedLengths[i]), binary.BigEndian.Uint16(packet[8:]))
+		assert.Len(t, packet, ddpHeaderLength+expectedLengths[i])
+	}
+	assert.Equal(t, byte(ddpVersion1), packets[0][0])
+	assert.Equal(t, byte(ddpVersion1), packets[1][0])
+	assert.Equal(t, byte(ddpVersion1|ddpPush), packets[2][0])
+	last := packets[2]
+	assert.Equal(t, []byte{255, 0, 0}, last[len(last)-3:])
+}
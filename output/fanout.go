@@ -0,0 +1,64 @@
+package output
+
+import (
+	"sync/atomic"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+// Fanout sends every frame to each of its outputs. Each output runs in its own goroutine
+// so a slow or disconnected output doesn't hold up the others.
+type Fanout struct {
+	managers []*manager
+	limiter  *usb.PowerLimiter
+}
+
+func NewFanout(outputs ...Output) *Fanout {
+	f := &Fanout{
+		managers: make([]*manager, len(outputs)),
+	}
+	for i, o := range outputs {
+		f.managers[i] = newManager(o)
+	}
+	return f
+}
+
+// Start runs every output until the process exits.
+func (f *Fanout) Start() {
+	for _, m := range f.managers {
+		go m.run()
+	}
+}
+
+// SetPowerLimiter dims frames that would draw more than the power budget before they're sent.
+func (f *Fanout) SetPowerLimiter(limiter *usb.PowerLimiter) {
+	f.limiter = limiter
+}
+
+// Send queues the frame for every output without waiting for them. An output that still has
+// an unsent frame drops it in favour of this one.
+func (f *Fanout) Send(renderPkg usb.RenderPackage) {
+	if f.limiter != nil {
+		renderPkg = f.limiter.Limit(renderPkg)
+	}
+	for _, m := range f.managers {
+		m.send(renderPkg)
+	}
+}
+
+// Dropped is the number of frames dropped across all outputs.
+func (f *Fanout) Dropped() uint64 {
+	var dropped uint64
+	for _, m := range f.managers {
+		dropped += atomic.LoadUint64(&m.dropped)
+	}
+	return dropped
+}
+
+func (f *Fanout) Statuses() []Status {
+	statuses := make([]Status, len(f.managers))
+	for i, m := range f.managers {
+		statuses[i] = m.status()
+	}
+	return statuses
+}
@@ -0,0 +1,168 @@
+package output
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+const (
+	equirectangularWidth  = 720
+	equirectangularHeight = 360
+	orthographicSize      = 480
+	ledRadiusDegrees      = 2.5
+)
+
+var (
+	background = color.RGBA{A: 255}
+	outline    = color.RGBA{R: 40, G: 40, B: 40, A: 255}
+)
+
+// Position is the location of a pixel on the globe in degrees.
+type Position struct {
+	Lat float64
+	Lon float64
+}
+
+type GlobeConfig struct {
+	// Dir is where the PNGs are written.
+	Dir string `json:"dir"`
+	// Every writes only every Nth frame. 0 and 1 both write every frame.
+	Every int `json:"every"`
+	// CenterLat and CenterLon are the point facing the camera in the orthographic view.
+	CenterLat float64 `json:"centerLat"`
+	CenterLon float64 `json:"centerLon"`
+}
+
+// Globe is a virtual globe that rasterizes frames to PNG files instead of lighting LEDs.
+// Each written frame produces an equirectangular map and an orthographic "view from space".
+type Globe struct {
+	tracker
+	encoder   usb.Encoder
+	config    GlobeConfig
+	positions []*Position
+	count     int
+}
+
+func NewGlobe(config GlobeConfig, positions []*Position) *Globe {
+	if config.Every < 1 {
+		config.Every = 1
+	}
+	return &Globe{
+		tracker:   newTracker("globe"),
+		config:    config,
+		positions: positions,
+	}
+}
+
+func (g *Globe) Open() error {
+	return g.opened(os.MkdirAll(g.config.Dir, 0755))
+}
+
+func (g *Globe) Write(renderPkg usb.RenderPackage) error {
+	frame := g.count
+	g.count++
+	if frame%g.config.Every != 0 {
+		return nil
+	}
+	rgb := g.encoder.RGB(renderPkg)
+	err := writePNG(filepath.Join(g.config.Dir, fmt.Sprintf("%06d-equirectangular.png", frame)), g.Equirectangular(rgb))
+	if err == nil {
+		err = writePNG(filepath.Join(g.config.Dir, fmt.Sprintf("%06d-orthographic.png", frame)), g.Orthographic(rgb))
+	}
+	return g.wrote(err)
+}
+
+func (g *Globe) Close() error {
+	return nil
+}
+
+// Equirectangular draws every pixel on a flat map, longitude across and latitude down.
+func (g *Globe) Equirectangular(rgb []byte) *image.RGBA {
+	img := newImage(equirectangularWidth, equirectangularHeight)
+	scale := float64(equirectangularWidth) / 360.0
+	for i, p := range g.positions {
+		if p == nil {
+			continue
+		}
+		x := (p.Lon + 180.0) * scale
+		y := (90.0 - p.Lat) * scale
+		fillCircle(img, x, y, ledRadiusDegrees*scale, pixelColor(rgb, i))
+	}
+	return img
+}
+
+// Orthographic draws the hemisphere facing CenterLat/CenterLon as it would look from space.
+func (g *Globe) Orthographic(rgb []byte) *image.RGBA {
+	img := newImage(orthographicSize, orthographicSize)
+	radius := orthographicSize/2.0 - 1.0
+	drawCircle(img, orthographicSize/2.0, orthographicSize/2.0, radius, outline)
+
+	lat0 := g.config.CenterLat * math.Pi / 180.0
+	lon0 := g.config.CenterLon * math.Pi / 180.0
+	ledRadius := radius * ledRadiusDegrees * math.Pi / 180.0
+	for i, p := range g.positions {
+		if p == nil {
+			continue
+		}
+		lat := p.Lat * math.Pi / 180.0
+		dLon := p.Lon*math.Pi/180.0 - lon0
+		cosC := math.Sin(lat0)*math.Sin(lat) + math.Cos(lat0)*math.Cos(lat)*math.Cos(dLon)
+		if cosC < 0 {
+			continue // on the far side
+		}
+		x := radius * math.Cos(lat) * math.Sin(dLon)
+		y := radius * (math.Cos(lat0)*math.Sin(lat) - math.Sin(lat0)*math.Cos(lat)*math.Cos(dLon))
+		fillCircle(img, orthographicSize/2.0+x, orthographicSize/2.0-y, ledRadius, pixelColor(rgb, i))
+	}
+	return img
+}
+
+func pixelColor(rgb []byte, i int) color.RGBA {
+	return color.RGBA{R: rgb[3*i], G: rgb[3*i+1], B: rgb[3*i+2], A: 255}
+}
+
+func newImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = background.R, background.G, background.B, background.A
+	}
+	return img
+}
+
+func fillCircle(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	for y := int(cy - r); y <= int(cy+r); y++ {
+		for x := int(cx - r); x <= int(cx+r); x++ {
+			dx, dy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			if dx*dx+dy*dy <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+func drawCircle(img *image.RGBA, cx, cy, r float64, c color.RGBA) {
+	steps := int(2 * math.Pi * r)
+	for i := 0; i < steps; i++ {
+		a := 2 * math.Pi * float64(i) / float64(steps)
+		img.SetRGBA(int(cx+r*math.Cos(a)), int(cy+r*math.Sin(a)), c)
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, img)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
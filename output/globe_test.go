@@ -0,0 +1,38 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobeWritesEveryNthFrame(t *testing.T) {
+	dir, err := ioutil.TempDir("", "globe")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	g := NewGlobe(GlobeConfig{Dir: dir, Every: 2}, []*Position{{Lat: 0, Lon: 0}, nil})
+	assert.NoError(t, g.Open())
+	renderPkg := usb.RenderPackage{Pixels: make([]colorful.Color, 2), Brightness: 1.0}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, g.Write(renderPkg))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.png"))
+	assert.Len(t, files, 4)
+	assert.Equal(t, uint64(2), g.Status().Frames)
+}
+
+func TestGlobeEquirectangular(t *testing.T) {
+	g := NewGlobe(GlobeConfig{}, []*Position{{Lat: 0, Lon: 0}, {Lat: 45, Lon: -90}})
+	img := g.Equirectangular([]byte{255, 0, 0, 0, 0, 255})
+
+	assert.Equal(t, uint8(255), img.RGBAAt(equirectangularWidth/2, equirectangularHeight/2).R)
+	assert.Equal(t, uint8(255), img.RGBAAt(equirectangularWidth/4, equirectangularHeight/4).B)
+	assert.Equal(t, background, img.RGBAAt(0, 0))
+}
@@ -0,0 +1,173 @@
+package output
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+const (
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateDisconnected = "disconnected" // the device isn't there, e.g. the Teensy is unplugged
+	StateError        = "error"        // the device is there but opening or writing failed
+
+	minBackoff    = 250 * time.Millisecond
+	maxBackoff    = 30 * time.Second
+	probeInterval = 500 * time.Millisecond
+)
+
+// Prober is implemented by outputs that can cheaply tell whether their device is attached.
+// While such an output is disconnected it's reopened as soon as the device shows up,
+// instead of waiting out the backoff.
+type Prober interface {
+	Present() bool
+}
+
+// manager keeps one output open, feeds it frames and reconnects it with exponential backoff.
+// Frames that arrive while the output isn't connected are discarded.
+type manager struct {
+	// dropped is updated atomically. It's first so it's 64 bit aligned for sync/atomic on 32 bit ARM.
+	dropped uint64
+	output  Output
+	frames  chan usb.RenderPackage
+
+	mu         sync.Mutex
+	state      string
+	connected  bool // whether the output has ever been connected
+	reconnects uint64
+	backoff    time.Duration
+}
+
+func newManager(o Output) *manager {
+	return &manager{
+		output:  o,
+		frames:  make(chan usb.RenderPackage, 1),
+		state:   StateConnecting,
+		backoff: minBackoff,
+	}
+}
+
+func (m *manager) run() {
+	for {
+		if err := m.output.Open(); err != nil {
+			m.output.Close()
+			m.failed(err)
+			m.wait()
+			continue
+		}
+		m.setState(StateConnected, nil)
+		wrote := false
+		for renderPkg := range m.frames {
+			if err := m.output.Write(renderPkg); err != nil {
+				m.output.Close()
+				m.failed(err)
+				// An output that opens but can't be written to would otherwise flap every frame.
+				m.wait()
+				break
+			}
+			if !wrote {
+				// Only a frame that got through shows the output works, so that's when the backoff starts again.
+				m.resetBackoff()
+				wrote = true
+			}
+		}
+	}
+}
+
+// send queues a frame, replacing the queued frame if the output hasn't taken it yet.
+// Only the Fanout sends, so once the old frame is gone there's room for the new one.
+func (m *manager) send(renderPkg usb.RenderPackage) {
+	select {
+	case m.frames <- renderPkg:
+		return
+	default:
+	}
+	select {
+	case <-m.frames:
+		atomic.AddUint64(&m.dropped, 1)
+	default:
+	}
+	m.frames <- renderPkg
+}
+
+func (m *manager) failed(err error) {
+	state := StateError
+	if prober, ok := m.output.(Prober); ok && !prober.Present() {
+		state = StateDisconnected
+	}
+	m.setState(state, err)
+}
+
+func (m *manager) setState(state string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state == m.state {
+		return
+	}
+	if err != nil {
+		log.Printf("%s is %s: %v", m.output.Status().Name, state, err)
+	} else {
+		log.Printf("%s is %s", m.output.Status().Name, state)
+	}
+	if state == StateConnected {
+		if m.connected {
+			m.reconnects++
+		}
+		m.connected = true
+	}
+	m.state = state
+}
+
+// wait drops frames until it's time to try opening the output again.
+func (m *manager) wait() {
+	backoff, disconnected := m.nextBackoff()
+
+	prober, canProbe := m.output.(Prober)
+	timeout := time.After(backoff)
+	probe := time.NewTicker(probeInterval)
+	defer probe.Stop()
+	for {
+		select {
+		case <-m.frames:
+		case <-probe.C:
+			if canProbe && disconnected && prober.Present() {
+				return
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+// nextBackoff returns how long to wait before the next attempt and doubles the wait after it.
+func (m *manager) nextBackoff() (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	backoff := m.backoff
+	m.backoff *= 2
+	if m.backoff > maxBackoff {
+		m.backoff = maxBackoff
+	}
+	return backoff, m.state == StateDisconnected
+}
+
+func (m *manager) resetBackoff() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.backoff = minBackoff
+}
+
+func (m *manager) status() Status {
+	status := m.output.Status()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status.State = m.state
+	status.Connected = m.state == StateConnected
+	status.Reconnects = m.reconnects
+	status.Dropped = atomic.LoadUint64(&m.dropped)
+	return status
+}
@@ -0,0 +1,131 @@
+package output
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyOutput fails to open until it's plugged in, and fails writes while unplugged.
+type flakyOutput struct {
+	tracker
+	mu      sync.Mutex
+	present bool
+	opens   int
+}
+
+func (f *flakyOutput) plug(present bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.present = present
+}
+
+func (f *flakyOutput) Present() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.present
+}
+
+func (f *flakyOutput) Open() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.opens++
+	if !f.present {
+		return errors.New("not plugged in")
+	}
+	return nil
+}
+
+func (f *flakyOutput) Write(renderPkg usb.RenderPackage) error {
+	if !f.Present() {
+		return f.wrote(errors.New("unplugged"))
+	}
+	return f.wrote(nil)
+}
+
+func (f *flakyOutput) Close() error { return nil }
+
+func waitForState(t *testing.T, m *manager, state string) Status {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if status := m.status(); status.State == state {
+			return status
+		}
+		m.frames <- usb.RenderPackage{}
+	}
+	t.Fatalf("%s never became %s, it is %s", m.output.Status().Name, state, m.status().State)
+	return Status{}
+}
+
+func TestManagerReconnectsWhenDeviceReturns(t *testing.T) {
+	o := &flakyOutput{tracker: newTracker("flaky")}
+	m := newManager(o)
+	go m.run()
+
+	waitForState(t, m, StateDisconnected)
+	o.plug(true)
+	status := waitForState(t, m, StateConnected)
+	assert.True(t, status.Connected)
+	assert.Equal(t, uint64(0), status.Reconnects)
+
+	o.plug(false)
+	waitForState(t, m, StateDisconnected)
+	o.plug(true)
+	status = waitForState(t, m, StateConnected)
+	assert.Equal(t, uint64(1), status.Reconnects)
+}
+
+func TestManagerBacksOff(t *testing.T) {
+	m := newManager(&flakyOutput{tracker: newTracker("flaky")})
+	m.setState(StateError, errors.New("broken"))
+	first, _ := m.nextBackoff()
+	second, _ := m.nextBackoff()
+	assert.Equal(t, minBackoff, first)
+	assert.Equal(t, 2*minBackoff, second)
+	for i := 0; i < 10; i++ {
+		m.nextBackoff()
+	}
+	last, disconnected := m.nextBackoff()
+	assert.Equal(t, maxBackoff, last)
+	assert.False(t, disconnected)
+
+	m.setState(StateConnected, nil)
+	assert.Equal(t, maxBackoff, m.backoff, "opening isn't enough to reset the backoff")
+	m.resetBackoff()
+	assert.Equal(t, minBackoff, m.backoff)
+}
+
+// unwritableOutput opens fine but fails every write.
+type unwritableOutput struct {
+	flakyOutput
+}
+
+func (u *unwritableOutput) Write(renderPkg usb.RenderPackage) error {
+	return u.wrote(errors.New("network unreachable"))
+}
+
+func TestManagerBacksOffFailingWrites(t *testing.T) {
+	o := &unwritableOutput{flakyOutput{tracker: newTracker("unwritable"), present: true}}
+	m := newManager(o)
+	go m.run()
+	for start := time.Now(); time.Since(start) < 500*time.Millisecond; time.Sleep(time.Millisecond) {
+		m.send(usb.RenderPackage{})
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	assert.True(t, o.opens <= 3, "opened %d times", o.opens)
+}
+
+func TestSendDropsOldestFrame(t *testing.T) {
+	m := newManager(&flakyOutput{tracker: newTracker("flaky")})
+	m.send(usb.RenderPackage{Brightness: 0.1})
+	m.send(usb.RenderPackage{Brightness: 0.2})
+	m.send(usb.RenderPackage{Brightness: 0.3})
+
+	assert.Equal(t, 0.3, (<-m.frames).Brightness)
+	assert.Equal(t, uint64(2), m.status().Dropped)
+}
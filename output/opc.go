@@ -0,0 +1,75 @@
+package output
+
+import (
+	"net"
+	"time"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+const (
+	opcDefaultPort    = "7890"
+	opcSetPixelColors = 0
+	opcDialTimeout    = 2 * time.Second
+	opcWriteTimeout   = 1 * time.Second
+)
+
+type OPCConfig struct {
+	// Address of the OPC server, e.g. "fadecandy.local:7890". The port defaults to 7890.
+	Address string `json:"address"`
+	// Channel to send to. 0 broadcasts to every channel.
+	Channel uint8 `json:"channel"`
+}
+
+// OPC sends frames to an Open Pixel Control server (e.g. fadecandy) over TCP.
+// See http://openpixelcontrol.org/
+type OPC struct {
+	tracker
+	encoder usb.Encoder
+	config  OPCConfig
+	conn    net.Conn
+}
+
+func NewOPC(config OPCConfig) *OPC {
+	if _, _, err := net.SplitHostPort(config.Address); err != nil {
+		config.Address = net.JoinHostPort(config.Address, opcDefaultPort)
+	}
+	return &OPC{
+		tracker: newTracker("opc " + config.Address),
+		config:  config,
+	}
+}
+
+func (o *OPC) Open() error {
+	conn, err := net.DialTimeout("tcp", o.config.Address, opcDialTimeout)
+	if err == nil {
+		o.conn = conn
+	}
+	return o.opened(err)
+}
+
+func (o *OPC) Write(renderPkg usb.RenderPackage) error {
+	o.conn.SetWriteDeadline(time.Now().Add(opcWriteTimeout))
+	_, err := o.conn.Write(opcMessage(o.config.Channel, o.encoder.RGB(renderPkg)))
+	return o.wrote(err)
+}
+
+func (o *OPC) Close() error {
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
+
+// opcMessage builds a "set pixel colors" message: channel, command, 16 bit big-endian length, then RGB data.
+func opcMessage(channel uint8, rgb []byte) []byte {
+	msg := make([]byte, 4+len(rgb))
+	msg[0] = channel
+	msg[1] = opcSetPixelColors
+	msg[2] = byte(len(rgb) >> 8)
+	msg[3] = byte(len(rgb))
+	copy(msg[4:], rgb)
+	return msg
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPixelCount = 1200
+
+func TestOPCSendsSetPixelColors(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan []byte)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		msg := make([]byte, 4+testPixelCount*3)
+		io.ReadFull(conn, msg)
+		received <- msg
+	}()
+
+	o := NewOPC(OPCConfig{Address: listener.Addr().String(), Channel: 3})
+	assert.NoError(t, o.Open())
+	defer o.Close()
+	pixels := make([]colorful.Color, testPixelCount)
+	pixels[1] = colorful.Color{R: 1.0}
+	assert.NoError(t, o.Write(usb.RenderPackage{Pixels: pixels, Brightness: 1.0}))
+
+	msg := <-received
+	assert.Equal(t, []byte{3, 0, 0x0e, 0x10}, msg[:4])
+	assert.Equal(t, []byte{0, 0, 0, 255, 0, 0}, msg[4:10])
+}
+
+func TestOPCDefaultPort(t *testing.T) {
+	assert.Equal(t, "fadecandy.local:7890", NewOPC(OPCConfig{Address: "fadecandy.local"}).config.Address)
+}
@@ -0,0 +1,105 @@
+// Package output contains the destinations that rendered frames can be sent to.
+package output
+
+import (
+	"sync"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/lucasb-eyer/go-colorful"
+)
+
+// Output is a destination for rendered frames, e.g. a Teensy on the USB bus.
+type Output interface {
+	// Open prepares the output for writing. It is called again after a failed Write.
+	Open() error
+	// Write sends a single frame to the output.
+	Write(renderPkg usb.RenderPackage) error
+	// Close releases anything held by the output. It is safe to call on an output that failed to open.
+	Close() error
+	// Status reports the name and counters of the output. State is filled in by the Fanout.
+	Status() Status
+}
+
+type Status struct {
+	Name string `json:"name"`
+	// State is one of StateConnecting, StateConnected, StateDisconnected or StateError.
+	State      string `json:"state"`
+	Connected  bool   `json:"connected"`
+	Reconnects uint64 `json:"reconnects"`
+	Frames     uint64 `json:"frames"`
+	// Dropped counts frames replaced by a newer frame before the output was ready for them.
+	Dropped   uint64 `json:"dropped"`
+	Errors    uint64 `json:"errors"`
+	LastError string `json:"lastError,omitempty"`
+	// Counters holds output specific packet counts, e.g. per universe.
+	Counters map[string]uint64 `json:"counters,omitempty"`
+}
+
+// tracker keeps the Status of an output up to date. Outputs embed it to implement Status().
+type tracker struct {
+	mu     sync.Mutex
+	status Status
+}
+
+func newTracker(name string) tracker {
+	return tracker{status: Status{Name: name}}
+}
+
+func (t *tracker) opened(err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recordError(err)
+	return err
+}
+
+func (t *tracker) wrote(err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		t.status.Frames++
+	}
+	t.recordError(err)
+	return err
+}
+
+func (t *tracker) count(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.status.Counters == nil {
+		t.status.Counters = make(map[string]uint64)
+	}
+	t.status.Counters[key]++
+}
+
+func (t *tracker) recordError(err error) {
+	if err != nil {
+		t.status.Errors++
+		t.status.LastError = err.Error()
+	}
+}
+
+func (t *tracker) Status() Status {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status := t.status
+	if t.status.Counters != nil {
+		status.Counters = make(map[string]uint64, len(t.status.Counters))
+		for k, v := range t.status.Counters {
+			status.Counters[k] = v
+		}
+	}
+	return status
+}
+
+// pixelRange returns count pixels starting at start, clipped to the frame.
+// A count of 0 means every pixel from start on.
+func pixelRange(pixels []colorful.Color, start, count int) []colorful.Color {
+	if start > len(pixels) {
+		start = len(pixels)
+	}
+	end := len(pixels)
+	if count > 0 && start+count < end {
+		end = start + count
+	}
+	return pixels[start:end]
+}
@@ -0,0 +1,202 @@
+package output
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+// E1.31 (ANSI E1.31-2016) constants.
+const (
+	sacnPort                = "5568"
+	sacnPixelsPerUniverse   = 170
+	sacnDataHeaderLength    = 126
+	sacnSyncLength          = 49
+	sacnDefaultPriority     = 100
+	sacnMaxPriority         = 200
+	sacnVectorRootData      = 0x00000004
+	sacnVectorRootExtended  = 0x00000008
+	sacnVectorFramingData   = 0x00000002
+	sacnVectorFramingSync   = 0x00000001
+	sacnVectorDMPSetProp    = 0x02
+	sacnDMPAddressType      = 0xa1
+	sacnDefaultSourceName   = "ledicious"
+	sacnMulticastAddrFormat = "239.255.%d.%d:" + sacnPort
+)
+
+var sacnPacketIdentifier = []byte("ASC-E1.17\x00\x00\x00")
+
+type SACNConfig struct {
+	// StartUniverse is the universe of the first 170 pixels. Each following 170 pixels
+	// go to the next universe. Defaults to 1.
+	StartUniverse uint16 `json:"startUniverse"`
+	// Unicast sends to this host instead of the per-universe multicast groups.
+	Unicast string `json:"unicast"`
+	// Priority from 0 to 200. Defaults to 100 when it isn't set.
+	Priority *int `json:"priority"`
+	// SyncUniverse, if set, makes receivers hold each frame until a sync packet
+	// is sent to this universe after the last data packet.
+	SyncUniverse uint16 `json:"syncUniverse"`
+	SourceName   string `json:"sourceName"`
+}
+
+// SACN sends frames to E1.31 (streaming ACN) pixel controllers, splitting the pixels
+// across consecutive universes.
+type SACN struct {
+	tracker
+	encoder      usb.Encoder
+	config       SACNConfig
+	priority     byte
+	cid          [16]byte
+	conn         net.PacketConn
+	unicastAddr  net.Addr
+	sequences    map[uint16]byte
+	syncSequence byte
+}
+
+// Check returns an error if the priority is out of range.
+func (c SACNConfig) Check() error {
+	if c.Priority != nil && (*c.Priority < 0 || *c.Priority > sacnMaxPriority) {
+		return fmt.Errorf("priority must be between 0 and %d, got %d", sacnMaxPriority, *c.Priority)
+	}
+	return nil
+}
+
+// NewSACN returns an sACN output. The config must have passed Check.
+func NewSACN(config SACNConfig) *SACN {
+	if config.StartUniverse == 0 {
+		config.StartUniverse = 1
+	}
+	if config.SourceName == "" {
+		config.SourceName = sacnDefaultSourceName
+	}
+	s := &SACN{
+		tracker:   newTracker("sacn"),
+		config:    config,
+		priority:  sacnDefaultPriority,
+		sequences: make(map[uint16]byte),
+	}
+	if config.Priority != nil {
+		s.priority = byte(*config.Priority)
+	}
+	rand.Read(s.cid[:])
+	return s
+}
+
+func (s *SACN) Open() error {
+	var err error
+	if s.config.Unicast != "" {
+		s.unicastAddr, err = resolveUDP(s.config.Unicast, sacnPort)
+		if err != nil {
+			return s.opened(err)
+		}
+	}
+	s.conn, err = net.ListenPacket("udp4", ":0")
+	return s.opened(err)
+}
+
+func (s *SACN) Write(renderPkg usb.RenderPackage) error {
+	rgb := s.encoder.RGB(renderPkg)
+	universe := s.config.StartUniverse
+	for start := 0; start < len(rgb); start += sacnPixelsPerUniverse * 3 {
+		end := start + sacnPixelsPerUniverse*3
+		if end > len(rgb) {
+			end = len(rgb)
+		}
+		if err := s.send(universe, s.dataPacket(universe, rgb[start:end])); err != nil {
+			return s.wrote(err)
+		}
+		universe++
+	}
+	if s.config.SyncUniverse != 0 {
+		if err := s.send(s.config.SyncUniverse, s.syncPacket()); err != nil {
+			return s.wrote(err)
+		}
+	}
+	return s.wrote(nil)
+}
+
+func (s *SACN) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *SACN) send(universe uint16, packet []byte) error {
+	addr := s.unicastAddr
+	if addr == nil {
+		var err error
+		addr, err = net.ResolveUDPAddr("udp4", fmt.Sprintf(sacnMulticastAddrFormat, universe>>8, universe&0xff))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := s.conn.WriteTo(packet, addr)
+	return err
+}
+
+func (s *SACN) dataPacket(universe uint16, channels []byte) []byte {
+	packet := make([]byte, sacnDataHeaderLength+len(channels))
+	s.rootLayer(packet, sacnVectorRootData)
+
+	// Framing layer
+	binary.BigEndian.PutUint16(packet[38:], flagsAndLength(len(packet)-38))
+	binary.BigEndian.PutUint32(packet[40:], sacnVectorFramingData)
+	copy(packet[44:108], s.config.SourceName)
+	packet[108] = s.priority
+	binary.BigEndian.PutUint16(packet[109:], s.config.SyncUniverse)
+	packet[111] = s.sequences[universe]
+	s.sequences[universe]++
+	packet[112] = 0 // options
+	binary.BigEndian.PutUint16(packet[113:], universe)
+
+	// DMP layer
+	binary.BigEndian.PutUint16(packet[115:], flagsAndLength(len(packet)-115))
+	packet[117] = sacnVectorDMPSetProp
+	packet[118] = sacnDMPAddressType
+	binary.BigEndian.PutUint16(packet[119:], 0) // first property address
+	binary.BigEndian.PutUint16(packet[121:], 1) // address increment
+	binary.BigEndian.PutUint16(packet[123:], uint16(len(channels)+1))
+	packet[125] = 0 // DMX start code
+	copy(packet[126:], channels)
+	return packet
+}
+
+func (s *SACN) syncPacket() []byte {
+	packet := make([]byte, sacnSyncLength)
+	s.rootLayer(packet, sacnVectorRootExtended)
+	binary.BigEndian.PutUint16(packet[38:], flagsAndLength(len(packet)-38))
+	binary.BigEndian.PutUint32(packet[40:], sacnVectorFramingSync)
+	packet[44] = s.syncSequence
+	s.syncSequence++
+	binary.BigEndian.PutUint16(packet[45:], s.config.SyncUniverse)
+	return packet
+}
+
+func (s *SACN) rootLayer(packet []byte, vector uint32) {
+	binary.BigEndian.PutUint16(packet[0:], 0x0010) // preamble size
+	binary.BigEndian.PutUint16(packet[2:], 0x0000) // postamble size
+	copy(packet[4:16], sacnPacketIdentifier)
+	binary.BigEndian.PutUint16(packet[16:], flagsAndLength(len(packet)-16))
+	binary.BigEndian.PutUint32(packet[18:], vector)
+	copy(packet[22:38], s.cid[:])
+}
+
+// flagsAndLength packs an ACN PDU length with the 0x7 flags in the high nibble.
+func flagsAndLength(length int) uint16 {
+	return 0x7000 | uint16(length&0x0fff)
+}
+
+// resolveUDP resolves "host" or "host:port", using defaultPort when none is given.
+func resolveUDP(address, defaultPort string) (*net.UDPAddr, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, defaultPort)
+	}
+	return net.ResolveUDPAddr("udp4", address)
+}
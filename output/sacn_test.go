@@ -0,0 +1,76 @@
+package output
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSACNSplitsUniverses(t *testing.T) {
+	listener, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	priority := 150
+	s := NewSACN(SACNConfig{Unicast: listener.LocalAddr().String(), StartUniverse: 10, SyncUniverse: 99, Priority: &priority})
+	assert.NoError(t, s.Open())
+	defer s.Close()
+
+	pixels := make([]colorful.Color, testPixelCount)
+	pixels[170] = colorful.Color{G: 1.0}
+	renderPkg := usb.RenderPackage{Pixels: pixels, Brightness: 1.0}
+	assert.NoError(t, s.Write(renderPkg))
+	assert.NoError(t, s.Write(renderPkg))
+
+	packets := readPackets(t, listener, 18)
+	for frame := 0; frame < 2; frame++ {
+		for i := 0; i < 8; i++ {
+			packet := packets[frame*9+i]
+			assert.Equal(t, uint16(10+i), binary.BigEndian.Uint16(packet[113:]))
+			assert.Equal(t, byte(frame), packet[111], "sequence number")
+			assert.Equal(t, byte(150), packet[108])
+			assert.Equal(t, uint16(99), binary.BigEndian.Uint16(packet[109:]))
+			assert.Equal(t, "ASC-E1.17", string(packet[4:13]))
+			if i < 7 {
+				assert.Len(t, packet, sacnDataHeaderLength+510)
+			} else {
+				assert.Len(t, packet, sacnDataHeaderLength+30)
+			}
+		}
+		sync := packets[frame*9+8]
+		assert.Len(t, sync, sacnSyncLength)
+		assert.Equal(t, uint32(sacnVectorFramingSync), binary.BigEndian.Uint32(sync[40:]))
+		assert.Equal(t, uint16(99), binary.BigEndian.Uint16(sync[45:]))
+	}
+	assert.Equal(t, []byte{0, 255, 0}, packets[1][126:129])
+}
+
+func TestSACNPriority(t *testing.T) {
+	zero, high, negative := 0, 201, -1
+	assert.NoError(t, SACNConfig{}.Check())
+	assert.NoError(t, SACNConfig{Priority: &zero}.Check())
+	assert.Error(t, SACNConfig{Priority: &high}.Check())
+	assert.Error(t, SACNConfig{Priority: &negative}.Check())
+
+	assert.Equal(t, byte(sacnDefaultPriority), NewSACN(SACNConfig{}).priority)
+	assert.Equal(t, byte(0), NewSACN(SACNConfig{Priority: &zero}).priority, "0 is a valid priority, not unset")
+}
+
+func readPackets(t *testing.T, conn net.PacketConn, count int) [][]byte {
+	packets := make([][]byte, 0, count)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for len(packets) < count {
+		buf := make([]byte, 2048)
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.NoError(t, err) {
+			return packets
+		}
+		packets = append(packets, buf[:n])
+	}
+	return packets
+}
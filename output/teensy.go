@@ -0,0 +1,94 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/drichelson/ledicious/usb"
+)
+
+const (
+	TransportUSB    = "usb"
+	TransportSerial = "serial"
+)
+
+type TeensyConfig struct {
+	// Transport is "usb" (libusb bulk transfers, the default) or "serial" (the CDC ACM tty).
+	Transport string `json:"transport"`
+	// Device is the serial port to use, e.g. /dev/ttyACM0. Defaults to the first /dev/ttyACM*.
+	Device string `json:"device"`
+	// Serial is the USB serial number of the Teensy. Defaults to the first Teensy found.
+	Serial string `json:"serial"`
+	// Start is the index of the first pixel this Teensy drives.
+	Start int `json:"start"`
+	// Count is the number of pixels this Teensy drives. 0 means every pixel from Start on.
+	Count int `json:"count"`
+}
+
+// Range returns the first pixel the Teensy drives and the one after its last, in a frame of pixelCount pixels.
+func (c TeensyConfig) Range(pixelCount int) (int, int) {
+	if c.Count == 0 {
+		return c.Start, pixelCount
+	}
+	return c.Start, c.Start + c.Count
+}
+
+// Check returns an error if the pixel range doesn't fit in a frame of pixelCount pixels.
+func (c TeensyConfig) Check(pixelCount int) error {
+	if c.Start < 0 || c.Count < 0 {
+		return fmt.Errorf("start and count must not be negative, got %d and %d", c.Start, c.Count)
+	}
+	if start, end := c.Range(pixelCount); start >= pixelCount || end > pixelCount {
+		return fmt.Errorf("pixels %d to %d are outside the %d pixels of a frame", start, end-1, pixelCount)
+	}
+	return nil
+}
+
+// teensyTransport moves framed pixel data to the Teensy.
+type teensyTransport interface {
+	Initialize() error
+	Render(renderPkg usb.RenderPackage) error
+	Close() error
+	Present() bool
+}
+
+// Teensy sends a range of the pixels to a Teensy, either with libusb bulk transfers or over its serial port.
+// Larger globes use one Teensy output per controller; each runs, and reconnects, on its own.
+type Teensy struct {
+	tracker
+	config    TeensyConfig
+	transport teensyTransport
+}
+
+func NewTeensy(config TeensyConfig) *Teensy {
+	name := "teensy"
+	if config.Serial != "" {
+		name += " " + config.Serial
+	}
+	t := &Teensy{config: config}
+	if config.Transport == TransportSerial {
+		t.tracker = newTracker(name + " serial")
+		t.transport = usb.NewSerial(config.Device)
+	} else {
+		t.tracker = newTracker(name)
+		t.transport = usb.NewDevice(config.Serial)
+	}
+	return t
+}
+
+func (t *Teensy) Open() error {
+	return t.opened(t.transport.Initialize())
+}
+
+func (t *Teensy) Write(renderPkg usb.RenderPackage) error {
+	renderPkg.Pixels = pixelRange(renderPkg.Pixels, t.config.Start, t.config.Count)
+	return t.wrote(t.transport.Render(renderPkg))
+}
+
+// Present reports whether the Teensy is plugged in, so the Fanout can reconnect as soon as it comes back.
+func (t *Teensy) Present() bool {
+	return t.transport.Present()
+}
+
+func (t *Teensy) Close() error {
+	return t.transport.Close()
+}
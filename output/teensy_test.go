@@ -0,0 +1,42 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/drichelson/ledicious/usb"
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingTransport struct {
+	rendered []usb.RenderPackage
+}
+
+func (r *recordingTransport) Initialize() error { return nil }
+func (r *recordingTransport) Close() error      { return nil }
+func (r *recordingTransport) Present() bool     { return true }
+func (r *recordingTransport) Render(renderPkg usb.RenderPackage) error {
+	r.rendered = append(r.rendered, renderPkg)
+	return nil
+}
+
+func TestTeensySendsItsPixelRange(t *testing.T) {
+	pixels := make([]colorful.Color, testPixelCount)
+	pixels[600] = colorful.Color{R: 1.0}
+	renderPkg := usb.RenderPackage{Pixels: pixels, Brightness: 1.0}
+
+	first, second := &recordingTransport{}, &recordingTransport{}
+	(&Teensy{config: TeensyConfig{Count: 600}, transport: first}).Write(renderPkg)
+	(&Teensy{config: TeensyConfig{Start: 600}, transport: second}).Write(renderPkg)
+
+	assert.Len(t, first.rendered[0].Pixels, 600)
+	assert.Len(t, second.rendered[0].Pixels, 600)
+	assert.Equal(t, colorful.Color{R: 1.0}, second.rendered[0].Pixels[0])
+}
+
+func TestPixelRangeClips(t *testing.T) {
+	pixels := make([]colorful.Color, 10)
+	assert.Len(t, pixelRange(pixels, 8, 5), 2)
+	assert.Len(t, pixelRange(pixels, 12, 5), 0)
+	assert.Len(t, pixelRange(pixels, 0, 0), 10)
+}
@@ -0,0 +1,76 @@
+// Package telemetry exports the go-metrics registry in the Prometheus text format.
+package telemetry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/launchdarkly/go-metrics"
+)
+
+const namespace = "ledicious"
+
+var quantiles = []float64{0.5, 0.9, 0.99}
+
+// WritePrometheus writes every metric in the registry in the Prometheus text exposition format.
+// Names are prefixed with "ledicious_" and dots become underscores, so "frame.generate" is
+// exported as ledicious_frame_generate_seconds. Timers are exported as summaries in seconds,
+// without a _sum because go-metrics only keeps a sample of the values.
+func WritePrometheus(w io.Writer, r metrics.Registry) error {
+	names := make([]string, 0)
+	all := make(map[string]interface{})
+	r.Each(func(name string, i interface{}) {
+		names = append(names, name)
+		all[name] = i
+	})
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		writeMetric(&buf, metricName(name), all[name])
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeMetric(w io.Writer, name string, i interface{}) {
+	switch m := i.(type) {
+	case metrics.Counter:
+		fmt.Fprintf(w, "# TYPE %s_total counter\n%s_total %d\n", name, name, m.Count())
+	case metrics.Meter:
+		fmt.Fprintf(w, "# TYPE %s_total counter\n%s_total %d\n", name, name, m.Snapshot().Count())
+	case metrics.Gauge:
+		fmt.Fprintf(w, "# TYPE %s gauge\n%s %d\n", name, name, m.Value())
+	case metrics.GaugeFloat64:
+		fmt.Fprintf(w, "# TYPE %s gauge\n%s %g\n", name, name, m.Value())
+	case metrics.Histogram:
+		h := m.Snapshot()
+		fmt.Fprintf(w, "# TYPE %s summary\n", name)
+		for i, v := range h.Percentiles(quantiles) {
+			fmt.Fprintf(w, "%s{quantile=\"%g\"} %g\n", name, quantiles[i], v)
+		}
+		fmt.Fprintf(w, "%s_count %d\n", name, h.Count())
+	case metrics.Timer:
+		t := m.Snapshot()
+		name += "_seconds"
+		fmt.Fprintf(w, "# TYPE %s summary\n", name)
+		for i, v := range t.Percentiles(quantiles) {
+			fmt.Fprintf(w, "%s{quantile=\"%g\"} %g\n", name, quantiles[i], v/1e9)
+		}
+		fmt.Fprintf(w, "%s_count %d\n", name, t.Count())
+	}
+}
+
+// metricName turns a go-metrics name into a valid Prometheus metric name.
+func metricName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	return namespace + "_" + name
+}
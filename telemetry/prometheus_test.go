@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.GetOrRegisterCounter("usb.render.errors", r).Inc(3)
+	metrics.GetOrRegisterGaugeFloat64("frame.fps", r).Update(29.5)
+	timer := metrics.GetOrRegisterTimer("frame.generate", r)
+	timer.Update(10 * time.Millisecond)
+	timer.Update(10 * time.Millisecond)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WritePrometheus(&buf, r))
+	assert.Equal(t, `# TYPE ledicious_frame_fps gauge
+ledicious_frame_fps 29.5
+# TYPE ledicious_frame_generate_seconds summary
+ledicious_frame_generate_seconds{quantile="0.5"} 0.01
+ledicious_frame_generate_seconds{quantile="0.9"} 0.01
+ledicious_frame_generate_seconds{quantile="0.99"} 0.01
+ledicious_frame_generate_seconds_count 2
+# TYPE ledicious_usb_render_errors_total counter
+ledicious_usb_render_errors_total 3
+`, buf.String())
+}
+
+func TestMetricName(t *testing.T) {
+	assert.Equal(t, "ledicious_http_requests", metricName("http.requests"))
+	assert.Equal(t, "ledicious_output_teensy_1_dropped", metricName("output.teensy-1.dropped"))
+}
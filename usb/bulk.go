@@ -0,0 +1,243 @@
+//go:build cgo
+// +build cgo
+
+package usb
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/drichelson/libusb"
+)
+
+//Teensy:
+// descriptor: &{Length:18 DescriptorType:Device descriptor. USBSpecification:0x0200 (2.00) DeviceClass:Communications class. DeviceSubClass:0 DeviceProtocol:0 MaxPacketSize0:64 VendorID:5824 ProductID:1155 DeviceReleaseNumber:0x0100 (1.00) ManufacturerIndex:1 ProductIndex:2 SerialNumberIndex:3 NumConfigurations:1}
+
+const (
+	teensyVendorID  = 5824
+	teensyProductID = 1155 // This seems to work with both Teensy 3.1 and 3.2
+)
+
+// Device is a Teensy claimed through libusb for bulk transfers.
+type Device struct {
+	serial  string
+	encoder Encoder
+	ctx     *libusb.Context
+	handle  *libusb.DeviceHandle
+}
+
+// NewDevice returns the Teensy with the given USB serial number. An empty serial matches the first Teensy found.
+func NewDevice(serial string) *Device {
+	showVersion.Do(ShowVersion)
+	return &Device{serial: serial}
+}
+
+var (
+	showVersion sync.Once
+	// probeCtx is kept open for the life of the process so Present doesn't set up libusb on every poll.
+	probeCtx  *libusb.Context
+	probeOnce sync.Once
+)
+
+func (d *Device) Initialize() error {
+	var err error
+	d.ctx, err = libusb.Init()
+	if err != nil {
+		return fmt.Errorf("error initializing libusb: %v", err)
+	}
+
+	usbDevice, err := d.open()
+	if err != nil {
+		return fmt.Errorf("error opening device: %v", err)
+	}
+	showInfo(usbDevice, d.handle, "Teensy")
+	kernelDriverActive, err := d.handle.KernelDriverActive(1)
+	if err != nil {
+		return fmt.Errorf("error getting kernel driver active state: %v", err)
+	}
+	if kernelDriverActive {
+		err = d.handle.DetachKernelDriver(1)
+		if err != nil {
+			return fmt.Errorf("error detaching kernel driver: %v", err)
+		}
+	}
+	err = d.handle.ClaimInterface(1)
+	if err != nil {
+		return fmt.Errorf("error claiming bulk transfer interface: %v", err)
+	}
+	return nil
+}
+
+// Present reports whether a matching Teensy is on the bus, without opening it unless a serial number has to be read.
+// The vendored libusb binding has no hotplug callbacks, so this is polled.
+func (d *Device) Present() bool {
+	probeOnce.Do(func() {
+		var err error
+		probeCtx, err = libusb.Init()
+		if err != nil {
+			log.Printf("Error initializing libusb for probing: %v", err)
+		}
+	})
+	if probeCtx == nil {
+		return false
+	}
+	usbDevices, err := probeCtx.GetDeviceList()
+	if err != nil {
+		return false
+	}
+	for _, usbDevice := range usbDevices {
+		descriptor, err := usbDevice.GetDeviceDescriptor()
+		if err != nil || descriptor.VendorID != teensyVendorID || descriptor.ProductID != teensyProductID {
+			continue
+		}
+		if d.serial == "" {
+			return true
+		}
+		handle, err := usbDevice.Open()
+		if err != nil {
+			continue
+		}
+		serial, _ := handle.GetStringDescriptorASCII(descriptor.SerialNumberIndex)
+		handle.Close()
+		if serial == d.serial {
+			return true
+		}
+	}
+	return false
+}
+
+// open finds and opens the Teensy with our serial number.
+func (d *Device) open() (*libusb.Device, error) {
+	if d.serial == "" {
+		usbDevice, handle, err := d.ctx.OpenDeviceWithVendorProduct(teensyVendorID, teensyProductID)
+		d.handle = handle
+		return usbDevice, err
+	}
+	usbDevices, err := d.ctx.GetDeviceList()
+	if err != nil {
+		return nil, err
+	}
+	for _, usbDevice := range usbDevices {
+		descriptor, err := usbDevice.GetDeviceDescriptor()
+		if err != nil || descriptor.VendorID != teensyVendorID || descriptor.ProductID != teensyProductID {
+			continue
+		}
+		handle, err := usbDevice.Open()
+		if err != nil {
+			continue
+		}
+		serial, _ := handle.GetStringDescriptorASCII(descriptor.SerialNumberIndex)
+		if serial == d.serial {
+			d.handle = handle
+			return usbDevice, nil
+		}
+		handle.Close()
+	}
+	return nil, fmt.Errorf("no Teensy with serial number %s", d.serial)
+}
+
+// Close releases the bulk transfer interface and closes the device and the libusb context.
+func (d *Device) Close() error {
+	if d.handle != nil {
+		d.handle.ReleaseInterface(1)
+		d.handle.Close()
+		d.handle = nil
+	}
+	if d.ctx != nil {
+		d.ctx.Exit()
+		d.ctx = nil
+	}
+	return nil
+}
+
+func (d *Device) Render(renderPkg RenderPackage) error {
+	//fmt.Printf("color count: %d\n", len(pixels))
+	data := d.encoder.frame(renderPkg)
+
+	addr := libusb.EndpointAddress(byte(3))
+	start := time.Now()
+
+	_, err := d.handle.BulkTransfer(addr, data, len(data), 20)
+	renderTimer.UpdateSince(start)
+	if err != nil {
+		renderErrors.Inc(1)
+		return fmt.Errorf("error bulk transferring: %v", err)
+	}
+	return nil
+}
+
+func ShowVersion() {
+	version := libusb.GetVersion()
+	log.Printf(
+		"Using libusb version %d.%d.%d (%d)\n",
+		version.Major,
+		version.Minor,
+		version.Micro,
+		version.Nano,
+	)
+}
+
+func showInfo(usbDevice *libusb.Device, usbDeviceHandle *libusb.DeviceHandle, name string) {
+	usbDeviceDescriptor, err := usbDevice.GetDeviceDescriptor()
+	if err != nil {
+		log.Printf("=> Failed opening the %s: %v\n", name, err)
+		return
+	}
+	serialnum, _ := usbDeviceHandle.GetStringDescriptorASCII(
+		usbDeviceDescriptor.SerialNumberIndex,
+	)
+	manufacturer, _ := usbDeviceHandle.GetStringDescriptorASCII(
+		usbDeviceDescriptor.ManufacturerIndex)
+	product, _ := usbDeviceHandle.GetStringDescriptorASCII(
+		usbDeviceDescriptor.ProductIndex)
+	log.Printf("Found %v %v S/N %s using Vendor ID %v and Product ID %v\n",
+		manufacturer,
+		product,
+		serialnum,
+		usbDeviceDescriptor.VendorID,
+		usbDeviceDescriptor.ProductID,
+	)
+	configDescriptor, err := usbDevice.GetActiveConfigDescriptor()
+	if err != nil {
+		log.Printf("Failed getting the active config: %v", err)
+		return
+	}
+	log.Printf("=> Max Power = %d mA\n",
+		configDescriptor.MaxPowerMilliAmperes)
+	var singularPlural string
+	if configDescriptor.NumInterfaces == 1 {
+		singularPlural = "interface"
+	} else {
+		singularPlural = "interfaces"
+	}
+	log.Printf("=> Found %d %s\n",
+		configDescriptor.NumInterfaces, singularPlural)
+
+	for i, supportedInterface := range configDescriptor.SupportedInterfaces {
+
+		log.Printf("=> %d interface has %d alternate settings.\n", i,
+			supportedInterface.NumAltSettings)
+		descriptor := supportedInterface.InterfaceDescriptors[0]
+		log.Printf("=> %d interface descriptor has a length of %d.\n", i, descriptor.Length)
+		log.Printf("=> %d interface descriptor is interface number %d.\n", i, descriptor.InterfaceNumber)
+		log.Printf("=> %d interface descriptor has %d endpoint(s).\n", i, descriptor.NumEndpoints)
+		log.Printf(
+			"   => USB-IF class %d, subclass %d, protocol %d.\n",
+			descriptor.InterfaceClass, descriptor.InterfaceSubClass, descriptor.InterfaceProtocol,
+		)
+		for j, endpoint := range descriptor.EndpointDescriptors {
+			log.Printf(
+				"   => Endpoint index %d on Interface %d has the following properties:\n",
+				j, descriptor.InterfaceNumber)
+			log.Printf("     => Address: %d (b%08b)\n", endpoint.EndpointAddress, endpoint.EndpointAddress)
+			log.Printf("       => Endpoint #: %d\n", endpoint.Number())
+			log.Printf("       => Direction: %s (%d)\n", endpoint.Direction(), endpoint.Direction())
+			log.Printf("     => Attributes: %d (b%08b) \n", endpoint.Attributes, endpoint.Attributes)
+			log.Printf("       => Transfer Type: %s (%d) \n", endpoint.TransferType(), endpoint.TransferType())
+			log.Printf("     => Max packet size: %d\n", endpoint.MaxPacketSize)
+		}
+		log.Println()
+	}
+}
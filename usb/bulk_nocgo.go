@@ -0,0 +1,38 @@
+//go:build !cgo
+// +build !cgo
+
+package usb
+
+import (
+	"errors"
+	"log"
+)
+
+var errNoCgo = errors.New("the libusb transport needs cgo, use the serial transport instead")
+
+// Device is only available when built with cgo.
+type Device struct{}
+
+func NewDevice(serial string) *Device {
+	return &Device{}
+}
+
+func (d *Device) Initialize() error {
+	return errNoCgo
+}
+
+func (d *Device) Present() bool {
+	return false
+}
+
+func (d *Device) Close() error {
+	return nil
+}
+
+func (d *Device) Render(renderPkg RenderPackage) error {
+	return errNoCgo
+}
+
+func ShowVersion() {
+	log.Println("Built without cgo, libusb is not available")
+}
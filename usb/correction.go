@@ -0,0 +1,83 @@
+package usb
+
+import "math"
+
+const (
+	defaultGamma = 1.08
+	// tableSize is the number of input steps per channel. Inputs between steps are rounded to the nearest.
+	tableSize = 4096
+	// fixedOne is 1.0 in the tables' fixed point output: bytes in the high 8 bits, a fraction of a byte in the low 8.
+	fixedOne = 255 << 8
+)
+
+// correction is used by every frame that's encoded. Change it with SetCorrection before rendering starts.
+var correction = NewCorrection(CorrectionConfig{})
+
+// RGBFactors holds one value per LED channel.
+type RGBFactors struct {
+	R float64 `json:"r"`
+	G float64 `json:"g"`
+	B float64 `json:"b"`
+}
+
+type CorrectionConfig struct {
+	// Gamma is the exponent applied to each channel. 0 means the default of 1.08.
+	Gamma RGBFactors `json:"gamma"`
+	// WhitePoint scales each channel at full brightness, e.g. to take the blue out of the globe's whites. 0 means 1.0.
+	WhitePoint RGBFactors `json:"whitePoint"`
+	// MinimumVisible is the lowest output the LEDs actually show (2/255 = .0078 on the globe).
+	// Outputs that would light an LED but fall below it are raised to it.
+	MinimumVisible float64 `json:"minimumVisible"`
+}
+
+// Correction holds precomputed per-channel lookup tables from linear color values to LED output.
+type Correction struct {
+	tables [3][]uint16
+}
+
+func NewCorrection(config CorrectionConfig) *Correction {
+	gammas := [3]float64{config.Gamma.R, config.Gamma.G, config.Gamma.B}
+	whites := [3]float64{config.WhitePoint.R, config.WhitePoint.G, config.WhitePoint.B}
+	c := &Correction{}
+	for channel := range c.tables {
+		gamma := gammas[channel]
+		if gamma == 0 {
+			gamma = defaultGamma
+		}
+		white := whites[channel]
+		if white == 0 {
+			white = 1.0
+		}
+		floor := config.MinimumVisible * fixedOne
+		table := make([]uint16, tableSize)
+		for i := range table {
+			out := white * math.Pow(float64(i)/(tableSize-1), gamma) * fixedOne
+			if out >= 1<<8 && out < floor { // would light the LED, but too dimly to see
+				out = floor
+			}
+			table[i] = uint16(math.Min(out, fixedOne))
+		}
+		c.tables[channel] = table
+	}
+	return c
+}
+
+// SetCorrection replaces the correction applied to every output. Call it before frames are rendered.
+func SetCorrection(config CorrectionConfig) {
+	correction = NewCorrection(config)
+}
+
+// fixed returns the corrected value of a channel (0 red, 1 green, 2 blue) in 8.8 fixed point.
+func (c *Correction) fixed(channel int, in float64) uint16 {
+	if !(in > 0) { // also catches NaN
+		return c.tables[channel][0]
+	}
+	if in >= 1 {
+		return c.tables[channel][tableSize-1]
+	}
+	return c.tables[channel][int(in*(tableSize-1)+0.5)]
+}
+
+func (c *Correction) rgb(r, g, b float64) (uint8, uint8, uint8) {
+	return uint8(c.fixed(0, r) >> 8), uint8(c.fixed(1, g) >> 8), uint8(c.fixed(2, b) >> 8)
+}
@@ -0,0 +1,46 @@
+package usb
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultCorrectionMatchesPowCurve(t *testing.T) {
+	c := NewCorrection(CorrectionConfig{})
+	for in := 0.0; in <= 1.0; in += 0.01 {
+		expected := 255.0 * math.Pow(in, defaultGamma)
+		r, g, b := c.rgb(in, in, in)
+		assert.InDelta(t, expected, float64(r), 1.5, "in: %v", in)
+		assert.Equal(t, r, g)
+		assert.Equal(t, r, b)
+	}
+}
+
+func TestCorrectionPerChannel(t *testing.T) {
+	c := NewCorrection(CorrectionConfig{
+		Gamma:      RGBFactors{R: 1.0, G: 2.0},
+		WhitePoint: RGBFactors{B: 0.5},
+	})
+	r, g, b := c.rgb(0.5, 0.5, 1.0)
+	assert.Equal(t, uint8(127), r)
+	assert.Equal(t, uint8(63), g)
+	assert.Equal(t, uint8(127), b)
+}
+
+func TestCorrectionMinimumVisible(t *testing.T) {
+	c := NewCorrection(CorrectionConfig{Gamma: RGBFactors{R: 1, G: 1, B: 1}, MinimumVisible: 2.0 / 255.0})
+	r, g, b := c.rgb(0, 0.5/255.0, 1.5/255.0)
+	assert.Equal(t, uint8(0), r, "black stays black")
+	assert.Equal(t, uint8(0), g, "rounds to off")
+	assert.Equal(t, uint8(2), b, "raised to the minimum visible value")
+}
+
+func TestCorrectionClamps(t *testing.T) {
+	c := NewCorrection(CorrectionConfig{})
+	r, g, b := c.rgb(-1, 2, math.NaN())
+	assert.Equal(t, uint8(0), r)
+	assert.Equal(t, uint8(255), g)
+	assert.Equal(t, uint8(0), b)
+}
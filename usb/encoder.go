@@ -0,0 +1,97 @@
+package usb
+
+import "sync/atomic"
+
+// Encoder turns frames into the RGB bytes sent to the LEDs, applying brightness and correction.
+// With dithering on, each subpixel's quantization error is carried over to the next frame,
+// so values that fall between two steps (or below the first one) still show on average.
+// An Encoder keeps per-pixel state, so each output needs its own.
+type Encoder struct {
+	residuals []uint16
+}
+
+// DitherStats measures how much of the requested light actually made it to the LEDs,
+// summed over every frame encoded so far, with dithering on or off.
+type DitherStats struct {
+	// Requested and Shown are in 1/256ths of a step.
+	Requested uint64 `json:"requested"`
+	Shown     uint64 `json:"shown"`
+	// SubStep counts subpixels asking for more than zero but less than one step.
+	SubStep uint64 `json:"subStep"`
+	// SubStepLit counts how many of those were lit anyway.
+	SubStepLit uint64 `json:"subStepLit"`
+}
+
+var ditherStats DitherStats
+
+// Dither returns the dither statistics of every encoder.
+func Dither() DitherStats {
+	return DitherStats{
+		Requested:  atomic.LoadUint64(&ditherStats.Requested),
+		Shown:      atomic.LoadUint64(&ditherStats.Shown),
+		SubStep:    atomic.LoadUint64(&ditherStats.SubStep),
+		SubStepLit: atomic.LoadUint64(&ditherStats.SubStepLit),
+	}
+}
+
+// Accuracy is the share of the requested light that was shown. Truncating without dithering
+// loses light in dark frames; with dithering it approaches 1.
+func (s DitherStats) Accuracy() float64 {
+	if s.Requested == 0 {
+		return 1.0
+	}
+	return float64(s.Shown) / float64(s.Requested)
+}
+
+// RGB returns 3 bytes (red, green, blue) per pixel.
+func (e *Encoder) RGB(renderPkg RenderPackage) []byte {
+	data := make([]byte, len(renderPkg.Pixels)*3)
+	e.encode(data, renderPkg)
+	return data
+}
+
+// frame returns the payload the Teensy expects: a '*', 238, 2 header followed by RGB bytes.
+func (e *Encoder) frame(renderPkg RenderPackage) []byte {
+	data := make([]byte, len(renderPkg.Pixels)*3+3)
+	data[0] = '*'
+	data[1] = 238
+	data[2] = 2
+	e.encode(data[3:], renderPkg)
+	return data
+}
+
+func (e *Encoder) encode(data []byte, renderPkg RenderPackage) {
+	if len(e.residuals) != len(data) {
+		e.residuals = make([]uint16, len(data))
+	}
+	var stats DitherStats
+	for i, c := range renderPkg.Pixels {
+		values := [3]float64{c.R * renderPkg.Brightness, c.G * renderPkg.Brightness, c.B * renderPkg.Brightness}
+		for channel, v := range values {
+			subpixel := 3*i + channel
+			fixed := uint32(correction.fixed(channel, v))
+			out := fixed
+			if renderPkg.Dither {
+				out += uint32(e.residuals[subpixel])
+				if out > fixedOne {
+					out = fixedOne
+				}
+				e.residuals[subpixel] = uint16(out & 0xff)
+			}
+			data[subpixel] = byte(out >> 8)
+
+			stats.Requested += uint64(fixed)
+			stats.Shown += uint64(data[subpixel]) << 8
+			if fixed > 0 && fixed < 1<<8 {
+				stats.SubStep++
+				if data[subpixel] > 0 {
+					stats.SubStepLit++
+				}
+			}
+		}
+	}
+	atomic.AddUint64(&ditherStats.Requested, stats.Requested)
+	atomic.AddUint64(&ditherStats.Shown, stats.Shown)
+	atomic.AddUint64(&ditherStats.SubStep, stats.SubStep)
+	atomic.AddUint64(&ditherStats.SubStepLit, stats.SubStepLit)
+}
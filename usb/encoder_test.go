@@ -0,0 +1,53 @@
+package usb
+
+import (
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoderWithoutDitherTruncates(t *testing.T) {
+	e := Encoder{}
+	renderPkg := RenderPackage{Pixels: []colorful.Color{{R: 1.0, G: 0.002}}, Brightness: 1.0}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, []byte{255, 0, 0}, e.RGB(renderPkg))
+	}
+}
+
+func TestEncoderDitherAveragesBelowOneStep(t *testing.T) {
+	SetCorrection(CorrectionConfig{Gamma: RGBFactors{R: 1, G: 1, B: 1}})
+	defer SetCorrection(CorrectionConfig{})
+
+	e := Encoder{}
+	renderPkg := RenderPackage{Pixels: []colorful.Color{{G: 0.25 / 255.0, B: 10.5 / 255.0}}, Brightness: 1.0, Dither: true}
+	var green, blue int
+	for i := 0; i < 100; i++ {
+		rgb := e.RGB(renderPkg)
+		green += int(rgb[1])
+		blue += int(rgb[2])
+	}
+	assert.InDelta(t, 25, green, 1)
+	assert.InDelta(t, 1050, blue, 7, "inputs are rounded to the nearest of 4096 table steps")
+}
+
+func TestDitherStatsAccuracy(t *testing.T) {
+	before := Dither()
+	renderPkg := RenderPackage{Pixels: []colorful.Color{{R: 0.003}}, Brightness: 1.0}
+	e := Encoder{}
+	for i := 0; i < 256; i++ {
+		e.RGB(renderPkg)
+	}
+	withoutDither := Dither()
+	renderPkg.Dither = true
+	for i := 0; i < 256; i++ {
+		e.RGB(renderPkg)
+	}
+	withDither := Dither()
+
+	truncated := DitherStats{Requested: withoutDither.Requested - before.Requested, Shown: withoutDither.Shown - before.Shown}
+	dithered := DitherStats{Requested: withDither.Requested - withoutDither.Requested, Shown: withDither.Shown - withoutDither.Shown}
+	assert.Equal(t, 0.0, truncated.Accuracy())
+	assert.InDelta(t, 1.0, dithered.Accuracy(), 0.01)
+	assert.True(t, withDither.SubStepLit > withoutDither.SubStepLit)
+}
@@ -0,0 +1,114 @@
+package usb
+
+import (
+	"sync"
+)
+
+const (
+	defaultMilliampsPerChannel = 20.0 // a WS2812 channel at full brightness
+	defaultIdleMilliamps       = 1.0
+	limitSearchSteps           = 10
+)
+
+type PowerConfig struct {
+	// BudgetMilliamps is the most the LEDs may draw. 0 means no limit, the draw is still estimated.
+	BudgetMilliamps float64 `json:"budgetMilliamps"`
+	// MilliampsPerChannel is the draw of one channel at full output. 0 means 20mA.
+	MilliampsPerChannel RGBFactors `json:"milliampsPerChannel"`
+	// IdleMilliamps is the draw of a pixel that's off. 0 means 1mA.
+	IdleMilliamps float64 `json:"idleMilliamps"`
+}
+
+type PowerStats struct {
+	BudgetMilliamps float64 `json:"budgetMilliamps"`
+	// EstimatedMilliamps is what the last frame would have drawn without limiting.
+	EstimatedMilliamps float64 `json:"estimatedMilliamps"`
+	// LimitedMilliamps is what the last frame draws after limiting.
+	LimitedMilliamps float64 `json:"limitedMilliamps"`
+	// Scale is the brightness multiplier applied to the last frame, 1 when it wasn't limited.
+	Scale         float64 `json:"scale"`
+	Frames        uint64  `json:"frames"`
+	LimitedFrames uint64  `json:"limitedFrames"`
+}
+
+// PowerLimiter estimates the current a frame draws and dims frames that would go over budget.
+type PowerLimiter struct {
+	config PowerConfig
+	mu     sync.Mutex
+	stats  PowerStats
+}
+
+func NewPowerLimiter(config PowerConfig) *PowerLimiter {
+	if config.MilliampsPerChannel.R == 0 {
+		config.MilliampsPerChannel.R = defaultMilliampsPerChannel
+	}
+	if config.MilliampsPerChannel.G == 0 {
+		config.MilliampsPerChannel.G = defaultMilliampsPerChannel
+	}
+	if config.MilliampsPerChannel.B == 0 {
+		config.MilliampsPerChannel.B = defaultMilliampsPerChannel
+	}
+	if config.IdleMilliamps == 0 {
+		config.IdleMilliamps = defaultIdleMilliamps
+	}
+	return &PowerLimiter{
+		config: config,
+		stats:  PowerStats{BudgetMilliamps: config.BudgetMilliamps, Scale: 1.0},
+	}
+}
+
+// Estimate returns the milliamps the frame draws once brightness and correction are applied.
+func (l *PowerLimiter) Estimate(renderPkg RenderPackage) float64 {
+	perChannel := [3]float64{l.config.MilliampsPerChannel.R, l.config.MilliampsPerChannel.G, l.config.MilliampsPerChannel.B}
+	var total [3]float64
+	for _, c := range renderPkg.Pixels {
+		total[0] += float64(correction.fixed(0, c.R*renderPkg.Brightness))
+		total[1] += float64(correction.fixed(1, c.G*renderPkg.Brightness))
+		total[2] += float64(correction.fixed(2, c.B*renderPkg.Brightness))
+	}
+	milliamps := l.config.IdleMilliamps * float64(len(renderPkg.Pixels))
+	for channel := range total {
+		milliamps += total[channel] / fixedOne * perChannel[channel]
+	}
+	return milliamps
+}
+
+// Limit returns the frame with its brightness lowered just enough to stay within budget.
+func (l *PowerLimiter) Limit(renderPkg RenderPackage) RenderPackage {
+	estimated := l.Estimate(renderPkg)
+	limited, scale := estimated, 1.0
+	if l.config.BudgetMilliamps > 0 && estimated > l.config.BudgetMilliamps {
+		// Correction isn't linear, so search for the largest scale that fits.
+		brightness := renderPkg.Brightness
+		low, high := 0.0, 1.0
+		for i := 0; i < limitSearchSteps; i++ {
+			mid := (low + high) / 2
+			renderPkg.Brightness = brightness * mid
+			if l.Estimate(renderPkg) > l.config.BudgetMilliamps {
+				high = mid
+			} else {
+				low = mid
+			}
+		}
+		scale = low
+		renderPkg.Brightness = brightness * scale
+		limited = l.Estimate(renderPkg)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Frames++
+	if scale < 1.0 {
+		l.stats.LimitedFrames++
+	}
+	l.stats.EstimatedMilliamps = estimated
+	l.stats.LimitedMilliamps = limited
+	l.stats.Scale = scale
+	return renderPkg
+}
+
+func (l *PowerLimiter) Stats() PowerStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
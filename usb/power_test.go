@@ -0,0 +1,42 @@
+package usb
+
+import (
+	"testing"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+func whiteFrame(count int) RenderPackage {
+	pixels := make([]colorful.Color, count)
+	for i := range pixels {
+		pixels[i] = colorful.Color{R: 1.0, G: 1.0, B: 1.0}
+	}
+	return RenderPackage{Pixels: pixels, Brightness: 1.0}
+}
+
+func TestPowerEstimate(t *testing.T) {
+	l := NewPowerLimiter(PowerConfig{})
+	assert.InDelta(t, 1200*61.0, l.Estimate(whiteFrame(1200)), 0.01)
+	assert.InDelta(t, 1200*1.0, l.Estimate(RenderPackage{Pixels: make([]colorful.Color, 1200), Brightness: 1.0}), 0.01)
+}
+
+func TestPowerLimitScalesFrameDown(t *testing.T) {
+	l := NewPowerLimiter(PowerConfig{BudgetMilliamps: 30000})
+	limited := l.Limit(whiteFrame(1200))
+
+	stats := l.Stats()
+	assert.True(t, limited.Brightness < 1.0)
+	assert.True(t, l.Estimate(limited) <= 30000)
+	assert.InDelta(t, 30000, stats.LimitedMilliamps, 300)
+	assert.InDelta(t, 73200.0, stats.EstimatedMilliamps, 0.01)
+	assert.Equal(t, uint64(1), stats.LimitedFrames)
+	assert.Equal(t, limited.Brightness, stats.Scale)
+}
+
+func TestPowerLimitLeavesFramesUnderBudget(t *testing.T) {
+	l := NewPowerLimiter(PowerConfig{BudgetMilliamps: 100000})
+	assert.Equal(t, 1.0, l.Limit(whiteFrame(1200)).Brightness)
+	assert.Equal(t, 1.0, l.Stats().Scale)
+	assert.Equal(t, uint64(0), l.Stats().LimitedFrames)
+}
@@ -0,0 +1,109 @@
+package usb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	serialDeviceGlob   = "/dev/ttyACM*"
+	serialWriteTimeout = 100 * time.Millisecond
+	linuxCBAUD         = 0x100f // not exported by the syscall package on every arch
+)
+
+// Serial sends frames to the Teensy through its CDC ACM serial port (/dev/ttyACM*).
+// Unlike the libusb transport it doesn't detach the kernel driver, so it doesn't need root.
+type Serial struct {
+	device  string
+	file    *os.File
+	encoder Encoder
+}
+
+// NewSerial returns a transport for the given device. An empty device uses the first /dev/ttyACM* found.
+func NewSerial(device string) *Serial {
+	return &Serial{device: device}
+}
+
+func (s *Serial) Initialize() error {
+	device := s.device
+	if device == "" {
+		matches, _ := filepath.Glob(serialDeviceGlob)
+		if len(matches) == 0 {
+			return fmt.Errorf("no device matches %s", serialDeviceGlob)
+		}
+		device = matches[0]
+	}
+	// Opened non-blocking so the file is pollable and write deadlines work.
+	fd, err := syscall.Open(device, syscall.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %v", device, err)
+	}
+	if err := makeRaw(fd); err != nil {
+		syscall.Close(fd)
+		return fmt.Errorf("error setting termios on %s: %v", device, err)
+	}
+	s.file = os.NewFile(uintptr(fd), device)
+	return nil
+}
+
+// Present reports whether the device node exists, which is how the kernel shows the Teensy is plugged in.
+func (s *Serial) Present() bool {
+	if s.device == "" {
+		matches, _ := filepath.Glob(serialDeviceGlob)
+		return len(matches) > 0
+	}
+	_, err := os.Stat(s.device)
+	return err == nil
+}
+
+func (s *Serial) Render(renderPkg RenderPackage) error {
+	start := time.Now()
+	s.file.SetWriteDeadline(start.Add(serialWriteTimeout))
+	_, err := s.file.Write(s.encoder.frame(renderPkg))
+	renderTimer.UpdateSince(start)
+	if err != nil {
+		renderErrors.Inc(1)
+		return fmt.Errorf("error writing to serial port: %v", err)
+	}
+	return nil
+}
+
+func (s *Serial) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// makeRaw puts the port into raw 8N1 mode, like cfmakeraw(3), so bytes aren't translated or echoed.
+func makeRaw(fd int) error {
+	var t syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, uintptr(unsafe.Pointer(&t))); err != nil {
+		return err
+	}
+	t.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	t.Oflag &^= syscall.OPOST
+	t.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	t.Cflag &^= syscall.CSIZE | syscall.PARENB | linuxCBAUD
+	t.Cflag |= syscall.CS8 | syscall.CLOCAL | syscall.CREAD | syscall.B115200
+	t.Ispeed = syscall.B115200
+	t.Ospeed = syscall.B115200
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+	return ioctl(fd, syscall.TCSETS, uintptr(unsafe.Pointer(&t)))
+}
+
+func ioctl(fd int, request, arg uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, arg)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package usb
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/lucasb-eyer/go-colorful"
+	"github.com/stretchr/testify/assert"
+)
+
+// openPty returns the master side of a new pseudo-terminal and the path of its slave.
+func openPty(t *testing.T) (*os.File, string) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals available: %v", err)
+	}
+	var unlock int32
+	var ptyNumber uint32
+	assert.NoError(t, ioctl(int(master.Fd()), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))))
+	assert.NoError(t, ioctl(int(master.Fd()), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&ptyNumber))))
+	return master, fmt.Sprintf("/dev/pts/%d", ptyNumber)
+}
+
+func TestSerialWritesFrame(t *testing.T) {
+	master, slave := openPty(t)
+	defer master.Close()
+
+	s := NewSerial(slave)
+	assert.NoError(t, s.Initialize())
+	defer s.Close()
+
+	// 10 is '\n', which a tty that isn't raw would turn into "\r\n".
+	newline := math.Pow(10.5/255.0, 1.0/1.08)
+	pixels := []colorful.Color{{R: 1.0}, {G: newline}}
+	assert.NoError(t, s.Render(RenderPackage{Pixels: pixels, Brightness: 1.0}))
+
+	data := make([]byte, 9)
+	_, err := io.ReadFull(master, data)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'*', 238, 2, 255, 0, 0, 0, 10, 0}, data)
+}
+
+func TestSerialMissingDevice(t *testing.T) {
+	assert.Error(t, NewSerial("/dev/does-not-exist").Initialize())
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+// +build !linux
+
+package usb
+
+import "errors"
+
+// Serial is only implemented on linux.
+type Serial struct{}
+
+func NewSerial(device string) *Serial {
+	return &Serial{}
+}
+
+func (s *Serial) Initialize() error {
+	return errors.New("the serial transport is only supported on linux")
+}
+
+func (s *Serial) Render(renderPkg RenderPackage) error {
+	return errors.New("the serial transport is only supported on linux")
+}
+
+func (s *Serial) Present() bool {
+	return false
+}
+
+func (s *Serial) Close() error {
+	return nil
+}
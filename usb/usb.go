@@ -1,182 +1,24 @@
 package usb
 
 import (
-	"log"
-	"math"
-
-	"github.com/drichelson/libusb"
+	"github.com/launchdarkly/go-metrics"
 	"github.com/lucasb-eyer/go-colorful"
-	"fmt"
-)
-
-//Teensy:
-// descriptor: &{Length:18 DescriptorType:Device descriptor. USBSpecification:0x0200 (2.00) DeviceClass:Communications class. DeviceSubClass:0 DeviceProtocol:0 MaxPacketSize0:64 VendorID:5824 ProductID:1155 DeviceReleaseNumber:0x0100 (1.00) ManufacturerIndex:1 ProductIndex:2 SerialNumberIndex:3 NumConfigurations:1}
-
-const (
-	teensyVendorID  = 5824
-	teensyProductID = 1155 // This seems to work with both Teensy 3.1 and 3.2
 )
 
 var (
-	ctx          *libusb.Context
-	deviceHandle *libusb.DeviceHandle
+	// renderTimer and renderErrors cover every transfer to a Teensy, whichever transport it uses.
+	renderTimer  = metrics.GetOrRegisterTimer("usb.render", metrics.DefaultRegistry)
+	renderErrors = metrics.GetOrRegisterCounter("usb.render.errors", metrics.DefaultRegistry)
 )
 
 type RenderPackage struct {
 	Pixels     []colorful.Color
 	Brightness float64
+	// Dither turns on temporal dithering for this frame.
+	Dither bool
 }
 
-func Initialize() error {
-	ShowVersion()
-	var err error
-	ctx, err = libusb.Init()
-	if err != nil {
-		log.Printf("Error initializing libusb: %v", err)
-		return err
-	}
-
-	_, deviceHandle, err = ctx.OpenDeviceWithVendorProduct(teensyVendorID, teensyProductID)
-	if err != nil {
-		log.Printf("Error opening device: %v", err)
-		return err
-	}
-	showInfo(ctx, "Teensy", teensyVendorID, teensyProductID)
-	kernelDriverActive, err := deviceHandle.KernelDriverActive(1)
-	if err != nil {
-		log.Printf("Error getting kernel driver active state: %v", err)
-		return err
-	}
-	if kernelDriverActive {
-		err = deviceHandle.DetachKernelDriver(1)
-		if err != nil {
-			log.Printf("Error detaching kernel driver: %v", err)
-			return err
-		}
-	}
-	err = deviceHandle.ClaimInterface(1)
-	if err != nil {
-		log.Printf("Error claiming bulk transfer interface: %v", err)
-		return err
-	}
-	return nil
-}
-
+// attempt to make brightness scale more linear
 func normalizeBrightness(color colorful.Color) (r, g, b uint8) {
-	return normalize(color.R), normalize(color.G), normalize(color.B)
-}
-
-//attempt to make brightness scale more linear
-func normalize(in float64) uint8 {
-	//TODO: use a lookup table instead? check performance on arm before/after
-	return uint8(255.0 * math.Pow(in, 1.08))
-}
-
-func Render(renderPkg RenderPackage) error {
-	//fmt.Printf("color count: %d\n", len(pixels))
-	pixels := renderPkg.Pixels
-	data := make([]byte, len(pixels)*3+3)
-	data[0] = '*'
-	data[1] = 238
-	data[2] = 2
-
-	for i, c := range pixels {
-		c.R = c.R * renderPkg.Brightness
-		c.G = c.G * renderPkg.Brightness
-		c.B = c.B * renderPkg.Brightness
-		c.RGB255()
-		r, g, b := normalizeBrightness(c)
-		data[3*i+3] = byte(r)   //Red
-		data[3*i+3+1] = byte(g) //Green
-		data[3*i+3+2] = byte(b) //Blue
-	}
-
-	addr := libusb.EndpointAddress(byte(3))
-	//start := time.Now()
-
-	_, err := deviceHandle.BulkTransfer(addr, data, len(data), 20)
-	if err != nil {
-		return fmt.Errorf("error bulk transferring: %v", err)
-	}
-	//log.Printf("Usb transfer took: %v\n", time.Since(start))
-	return nil
-}
-
-func ShowVersion() {
-	version := libusb.GetVersion()
-	log.Printf(
-		"Using libusb version %d.%d.%d (%d)\n",
-		version.Major,
-		version.Minor,
-		version.Micro,
-		version.Nano,
-	)
-}
-
-func showInfo(ctx *libusb.Context, name string, vendorID, productID uint16) {
-	log.Printf("Let's open the %s using the Vendor and Product IDs\n", name)
-	usbDevice, usbDeviceHandle, err := ctx.OpenDeviceWithVendorProduct(vendorID, productID)
-	if err != nil {
-		log.Fatalf("Could not open device with error: %v\n", err)
-	}
-	usbDeviceDescriptor, err := usbDevice.GetDeviceDescriptor()
-	if err != nil {
-		log.Fatalf("=> Failed opening the %s: %v\n", name, err)
-		return
-	}
-	defer usbDeviceHandle.Close()
-	serialnum, _ := usbDeviceHandle.GetStringDescriptorASCII(
-		usbDeviceDescriptor.SerialNumberIndex,
-	)
-	manufacturer, _ := usbDeviceHandle.GetStringDescriptorASCII(
-		usbDeviceDescriptor.ManufacturerIndex)
-	product, _ := usbDeviceHandle.GetStringDescriptorASCII(
-		usbDeviceDescriptor.ProductIndex)
-	log.Printf("Found %v %v S/N %s using Vendor ID %v and Product ID %v\n",
-		manufacturer,
-		product,
-		serialnum,
-		vendorID,
-		productID,
-	)
-	configDescriptor, err := usbDevice.GetActiveConfigDescriptor()
-	if err != nil {
-		log.Fatalf("Failed getting the active config: %v", err)
-	}
-	log.Printf("=> Max Power = %d mA\n",
-		configDescriptor.MaxPowerMilliAmperes)
-	var singularPlural string
-	if configDescriptor.NumInterfaces == 1 {
-		singularPlural = "interface"
-	} else {
-		singularPlural = "interfaces"
-	}
-	log.Printf("=> Found %d %s\n",
-		configDescriptor.NumInterfaces, singularPlural)
-
-	for i, supportedInterface := range configDescriptor.SupportedInterfaces {
-
-		log.Printf("=> %d interface has %d alternate settings.\n", i,
-			supportedInterface.NumAltSettings)
-		descriptor := supportedInterface.InterfaceDescriptors[0]
-		log.Printf("=> %d interface descriptor has a length of %d.\n", i, descriptor.Length)
-		log.Printf("=> %d interface descriptor is interface number %d.\n", i, descriptor.InterfaceNumber)
-		log.Printf("=> %d interface descriptor has %d endpoint(s).\n", i, descriptor.NumEndpoints)
-		log.Printf(
-			"   => USB-IF class %d, subclass %d, protocol %d.\n",
-			descriptor.InterfaceClass, descriptor.InterfaceSubClass, descriptor.InterfaceProtocol,
-		)
-		for j, endpoint := range descriptor.EndpointDescriptors {
-			log.Printf(
-				"   => Endpoint index %d on Interface %d has the following properties:\n",
-				j, descriptor.InterfaceNumber)
-			log.Printf("     => Address: %d (b%08b)\n", endpoint.EndpointAddress, endpoint.EndpointAddress)
-			log.Printf("       => Endpoint #: %d\n", endpoint.Number())
-			log.Printf("       => Direction: %s (%d)\n", endpoint.Direction(), endpoint.Direction())
-			log.Printf("     => Attributes: %d (b%08b) \n", endpoint.Attributes, endpoint.Attributes)
-			log.Printf("       => Transfer Type: %s (%d) \n", endpoint.TransferType(), endpoint.TransferType())
-			log.Printf("     => Max packet size: %d\n", endpoint.MaxPacketSize)
-		}
-		log.Println()
-	}
+	return correction.rgb(color.R, color.G, color.B)
 }